@@ -0,0 +1,123 @@
+// Command apigen generates the API Gateway, Function URL or ALB
+// infrastructure for a function serving an OpenAPI spec with httpbridge, from
+// the spec oapi-codegen generates the server from:
+//
+//	go run github.com/geode-io/golambdas/cmd/apigen -spec api.yaml -target http \
+//		-function ApiFunction -authorizer bearerAuth=AuthorizerFunction \
+//		-definition openapi.aws.yaml -template api.template.yaml
+//
+// For REST and HTTP APIs, it writes the spec with a Lambda proxy integration
+// for every operation and Lambda authorizers for the security schemes given,
+// for the API resource of the SAM template fragment to include. For all
+// targets, the fragment's Resources and Outputs are to be merged into the
+// template defining the function.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+
+	"github.com/geode-io/golambdas/internal/apigen"
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "apigen:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("apigen", flag.ContinueOnError)
+	specPath := flags.String("spec", "", "OpenAPI spec to read, as JSON or YAML")
+	target := flags.String("target", string(apigen.TargetHTTPAPI), "what invokes the function: rest, http, url or alb")
+	function := flags.String("function", "ApiFunction", "logical ID of the function serving the API")
+	definitionPath := flags.String("definition", "openapi.aws.yaml",
+		"OpenAPI document to write for REST and HTTP APIs, as JSON or YAML")
+	templatePath := flags.String("template", "-", "SAM template fragment to write, or - for stdout")
+	stageName := flags.String("stage", "prod", "stage of REST APIs")
+	urlAuthType := flags.String("url-auth-type", "NONE", "AuthType of Function URLs, NONE or AWS_IAM")
+	priority := flags.Int("priority", 1, "priority of the first ALB listener rule")
+	authorizers := map[string]string{}
+	flags.Func("authorizer", "scheme=FunctionLogicalID, authorizing a security scheme with a Lambda authorizer",
+		func(value string) error {
+			scheme, authorizer, ok := strings.Cut(value, "=")
+			if !ok || scheme == "" || authorizer == "" {
+				return fmt.Errorf("want scheme=FunctionLogicalID, got %q", value)
+			}
+			authorizers[scheme] = authorizer
+			return nil
+		})
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *specPath == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	spec, err := openapi3.NewLoader().LoadFromFile(*specPath)
+	if err != nil {
+		return fmt.Errorf("failed to load spec: %w", err)
+	}
+
+	// the template includes the definition from a path relative to itself
+	location := *definitionPath
+	if *templatePath != "-" {
+		if location, err = filepath.Rel(filepath.Dir(*templatePath), *definitionPath); err != nil {
+			return fmt.Errorf("failed to locate definition: %w", err)
+		}
+	}
+	definition, template, err := apigen.Generate(spec, apigen.Config{
+		Target:             apigen.Target(*target),
+		Function:           *function,
+		Authorizers:        authorizers,
+		DefinitionLocation: filepath.ToSlash(location),
+		StageName:          *stageName,
+		URLAuthType:        *urlAuthType,
+		ListenerPriority:   *priority,
+	})
+	if err != nil {
+		return err
+	}
+
+	if definition != nil {
+		if err := write(*definitionPath, definition); err != nil {
+			return fmt.Errorf("failed to write definition: %w", err)
+		}
+	}
+	if err := write(*templatePath, template); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
+
+// write writes v to path as JSON if it ends in .json, and as YAML otherwise.
+func write(path string, v any) error {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(path, ".json") {
+		if body, err = yaml.JSONToYAML(body); err != nil {
+			return err
+		}
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+	return os.WriteFile(path, body, 0o644) //nolint:gosec // templates are not secret
+}
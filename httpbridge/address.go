@@ -0,0 +1,147 @@
+package httpbridge
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// none of the event sources tell us which port the client connected from, so
+// RemoteAddr carries a zero port to keep the usual ip:port shape
+const unknownPort = "0"
+
+func forwardedScheme(headers http.Header) string {
+	proto, _, _ := strings.Cut(headers.Get(forwardedProtoHeader), ",")
+	switch proto = strings.ToLower(strings.TrimSpace(proto)); proto {
+	case schemeHTTP, schemeHTTPS:
+		return proto
+	}
+	// API Gateway and function URLs only ever terminate TLS
+	return schemeHTTPS
+}
+
+func forwardedHost(headers http.Header, domainName string, scheme string) string {
+	host := headers.Get(hostHeader)
+	if host == "" {
+		host = domainName
+	}
+	if host == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	port, _, _ := strings.Cut(headers.Get(forwardedPortHeader), ",")
+	port = strings.TrimSpace(port)
+	switch {
+	case port == "",
+		scheme == schemeHTTPS && port == "443",
+		scheme == schemeHTTP && port == "80":
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+func remoteAddr(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	if addrPort, err := netip.ParseAddrPort(ip); err == nil {
+		return addrPort.String()
+	}
+	return net.JoinHostPort(ip, unknownPort)
+}
+
+func forwardedFor(headers http.Header) []string {
+	var chain []string
+	for _, line := range headers.Values(forwardedForHeader) {
+		for _, hop := range strings.Split(line, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				chain = append(chain, hop)
+			}
+		}
+	}
+	return chain
+}
+
+// TrustedProxies decides how far back an X-Forwarded-For chain can be
+// believed. The zero value trusts nothing but the event source itself.
+type TrustedProxies struct {
+	// Hops is the number of proxies directly in front of the event source,
+	// e.g. 1 for a CloudFront distribution, whose entries are always trusted.
+	Hops int
+	// Prefixes are networks whose addresses are trusted wherever they appear
+	// in the chain.
+	Prefixes []netip.Prefix
+}
+
+func (p TrustedProxies) trusts(hop int, addr netip.Addr) bool {
+	if hop < p.Hops {
+		return true
+	}
+	for _, prefix := range p.Prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve walks the X-Forwarded-For chain from the right, starting at peer,
+// and returns the first address not vouched for by a trusted proxy.
+func (p TrustedProxies) Resolve(peer string, chain []string) string {
+	if peer != "" && (len(chain) == 0 || chain[len(chain)-1] != peer) {
+		chain = append(chain[:len(chain):len(chain)], peer)
+	}
+
+	client := ""
+	for hop := 0; hop < len(chain); hop++ {
+		addr, ok := parseHop(chain[len(chain)-1-hop])
+		if !ok {
+			// anything left of garbage is as good as made up
+			break
+		}
+		client = addr.String()
+		if !p.trusts(hop, addr) {
+			break
+		}
+	}
+	return client
+}
+
+func parseHop(hop string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.Trim(hop, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// ClientIP rewrites r.RemoteAddr to the client address resolved from the
+// X-Forwarded-For chain according to policy.
+func ClientIP(policy TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				peer, port = r.RemoteAddr, unknownPort
+			}
+
+			client := policy.Resolve(peer, forwardedFor(r.Header))
+			if client == "" || client == peer {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.RemoteAddr = net.JoinHostPort(client, port)
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package httpbridge
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+var (
+	// ErrNotFound is what handlers return, wrapped or not, for resources that
+	// don't exist; the default response error handler answers it with a 404.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is what handlers return for requests that conflict with the
+	// state of a resource; the default response error handler answers it with
+	// a 409.
+	ErrConflict = errors.New("conflict")
+)
+
+// ValidationError is what handlers return for requests that are well formed
+// but can't be processed as they are. The default response error handler
+// answers it with a 422 problem, whose detail and errors are the ones here,
+// so they must be fit for clients.
+type ValidationError struct {
+	Detail string
+	Errors []ProblemError
+}
+
+func (e *ValidationError) Error() string {
+	if e.Detail != "" {
+		return "validation failed: " + e.Detail
+	}
+	return "validation failed"
+}
+
+// ErrorHandlerFunc handles errors in strict servers, as the fields of the
+// StrictHTTPServerOptions oapi-codegen generates do.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// strictServerOptions is satisfied by the StrictHTTPServerOptions
+// oapi-codegen generates in each API's package.
+type strictServerOptions interface {
+	~struct {
+		RequestErrorHandlerFunc  func(w http.ResponseWriter, r *http.Request, err error)
+		ResponseErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+	}
+}
+
+// WriteRequestErrorProblem is the default request error handler. Strict
+// servers call it with the errors decoding request bodies, which it answers
+// with a 400 problem.
+func WriteRequestErrorProblem(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	lambdalog.FromContext(ctx).WarnContext(ctx, "failed to decode request", "error", err)
+	WriteProblem(w, NewProblem(ctx, http.StatusBadRequest, "malformed request body"))
+}
+
+// WriteResponseErrorProblem is the default response error handler. Strict
+// servers call it with the errors handlers return, which it answers with a
+// 404 for ErrNotFound, a 409 for ErrConflict, a 422 for a ValidationError and
+// a 500 otherwise. Like RenderProblem, it never exposes err itself.
+func WriteResponseErrorProblem(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		problem := NewProblem(ctx, http.StatusUnprocessableEntity, validationErr.Detail)
+		problem.Errors = validationErr.Errors
+		WriteProblem(w, problem)
+	case errors.Is(err, ErrNotFound):
+		WriteProblem(w, NewProblem(ctx, http.StatusNotFound, ""))
+	case errors.Is(err, ErrConflict):
+		WriteProblem(w, NewProblem(ctx, http.StatusConflict, ""))
+	default:
+		lambdalog.FromContext(ctx).ErrorContext(ctx, "handler failed", "error", err)
+		WriteProblem(w, NewProblem(ctx, http.StatusInternalServerError, ""))
+	}
+}
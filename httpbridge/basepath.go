@@ -0,0 +1,148 @@
+package httpbridge
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultStage = "$default"
+
+type basePathContextKey struct{}
+
+// BasePathFromContext returns the prefix StripBasePath removed from the
+// request path, e.g. "/prod" or "/v1".
+func BasePathFromContext(ctx context.Context) string {
+	basePath, _ := ctx.Value(basePathContextKey{}).(string)
+	return basePath
+}
+
+// StripBasePath removes the API Gateway stage and basePath from the request
+// path, so routes match the same way whether the API is reached through its
+// execute-api endpoint or a custom domain base path mapping. An empty basePath
+// is derived by matching the event's resource or route key against the path.
+func StripBasePath(basePath string) func(http.Handler) http.Handler {
+	basePath = strings.Trim(basePath, "/")
+	if basePath != "" {
+		basePath = "/" + basePath
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event := eventFromContext(r.Context())
+			prefix, path := "", r.URL.Path
+			if stage := eventStage(event); stage != "" {
+				if rest, ok := cutPathPrefix(path, stage); ok {
+					prefix, path = stage, rest
+				}
+			}
+
+			base := basePath
+			if base == "" {
+				base = derivedBasePath(event, path)
+			}
+			if rest, ok := cutPathPrefix(path, base); ok {
+				prefix, path = prefix+base, rest
+			}
+
+			if prefix == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := r.WithContext(context.WithValue(r.Context(), basePathContextKey{}, prefix))
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = path
+			r2.URL.RawPath = ""
+			if r.URL.RawPath != "" {
+				escapedPrefix := (&url.URL{Path: prefix}).EscapedPath()
+				if rawPath, ok := cutPathPrefix(r.URL.RawPath, escapedPrefix); ok {
+					r2.URL.RawPath = rawPath
+				}
+			}
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// HTTP APIs keep a named stage in the raw path, REST APIs never do.
+func eventStage(event lambdaHTTPRequest) string {
+	if v2, ok := event.(*apiGatewayV2Request); ok {
+		if stage := v2.RequestContext.Stage; stage != "" && stage != defaultStage {
+			return "/" + stage
+		}
+	}
+	return ""
+}
+
+func derivedBasePath(event lambdaHTTPRequest, path string) string {
+	template, params := eventRoute(event)
+	route, ok := renderRoute(template, params)
+	if !ok {
+		return ""
+	}
+	if route == "/" {
+		return strings.TrimSuffix(path, "/")
+	}
+	if !strings.HasSuffix(path, route) {
+		return ""
+	}
+	return strings.TrimSuffix(path, route)
+}
+
+// eventRoute returns the resource or route template API Gateway matched, such
+// as "/pets/{id}", and the path parameters it extracted.
+func eventRoute(event lambdaHTTPRequest) (string, map[string]string) {
+	switch e := event.(type) {
+	case *apiGatewayV1Request:
+		return e.Resource, e.PathParameters
+	case *apiGatewayV2Request:
+		// route keys look like "GET /pets/{id}", except for "$default"
+		_, template, _ := strings.Cut(e.RouteKey, " ")
+		return template, e.PathParameters
+	}
+	return "", nil
+}
+
+// renderRoute fills a resource template such as "/pets/{id}" or "/{proxy+}"
+// with the event's path parameters.
+func renderRoute(template string, params map[string]string) (string, bool) {
+	if !strings.HasPrefix(template, "/") {
+		return "", false
+	}
+
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.Trim(segment, "{}")
+		greedy := strings.HasSuffix(name, "+")
+		value, ok := params[strings.TrimSuffix(name, "+")]
+		if !ok {
+			return "", false
+		}
+		if greedy {
+			value = strings.TrimPrefix(value, "/")
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// cutPathPrefix removes prefix from path only on a segment boundary.
+func cutPathPrefix(path string, prefix string) (string, bool) {
+	if prefix == "" || !strings.HasPrefix(path, prefix) {
+		return path, false
+	}
+	rest := path[len(prefix):]
+	switch {
+	case rest == "":
+		return "/", true
+	case strings.HasPrefix(rest, "/"):
+		return rest, true
+	}
+	return path, false
+}
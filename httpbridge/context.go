@@ -0,0 +1,59 @@
+package httpbridge
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type eventContextKey struct{}
+
+func withEvent(ctx context.Context, event lambdaHTTPRequest) context.Context {
+	return context.WithValue(ctx, eventContextKey{}, event)
+}
+
+func eventFromContext(ctx context.Context) lambdaHTTPRequest {
+	event, _ := ctx.Value(eventContextKey{}).(lambdaHTTPRequest)
+	return event
+}
+
+// RouteFromContext returns the resource or route template API Gateway
+// matched the request against, such as "/pets/{id}". It is empty for ALB
+// events and catch-all routes like "$default".
+func RouteFromContext(ctx context.Context) string {
+	template, _ := eventRoute(eventFromContext(ctx))
+	return template
+}
+
+// Route returns the route template r was served for, once it has been: the
+// pattern a ServeMux matched, without its method and host, or else
+// RouteFromContext's.
+func Route(r *http.Request) string {
+	if i := strings.Index(r.Pattern, "/"); i >= 0 {
+		return r.Pattern[i:]
+	}
+	return RouteFromContext(r.Context())
+}
+
+// APIGatewayV2RequestFromContext returns the HTTP API event the request was
+// canonized from, if any.
+func APIGatewayV2RequestFromContext(ctx context.Context) (*events.APIGatewayV2HTTPRequest, bool) {
+	event, ok := eventFromContext(ctx).(*apiGatewayV2Request)
+	return (*events.APIGatewayV2HTTPRequest)(event), ok
+}
+
+// APIGatewayRequestFromContext returns the REST API event the request was
+// canonized from, if any.
+func APIGatewayRequestFromContext(ctx context.Context) (*events.APIGatewayProxyRequest, bool) {
+	event, ok := eventFromContext(ctx).(*apiGatewayV1Request)
+	return (*events.APIGatewayProxyRequest)(event), ok
+}
+
+// ALBRequestFromContext returns the ALB target group event the request was
+// canonized from, if any.
+func ALBRequestFromContext(ctx context.Context) (*events.ALBTargetGroupRequest, bool) {
+	event, ok := eventFromContext(ctx).(*albRequest)
+	return (*events.ALBTargetGroupRequest)(event), ok
+}
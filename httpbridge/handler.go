@@ -1,14 +1,23 @@
 package httpbridge
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/oapi-codegen/runtime/strictmiddleware/nethttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+
+	"github.com/geode-io/golambdas/lambdalog"
+	"github.com/geode-io/golambdas/lambdamiddleware"
+	"github.com/geode-io/golambdas/lambdaotel"
 )
 
 func ServeAPI[STRICTAPI any, API any](
@@ -18,71 +27,67 @@ func ServeAPI[STRICTAPI any, API any](
 		[]nethttp.StrictHTTPMiddlewareFunc,
 	) API,
 	serve func(API) http.Handler,
-	opts ...APIOption,
+	opts ...Option,
 ) lambda.Handler {
-	useOpts := apiOptions{}
-	for _, opt := range opts {
-		opt(&useOpts)
-	}
+	useOpts := newOptions(opts)
+	return ServeHTTP(serve(configureHandler(api, useOpts.strictMiddlewares)), opts...)
+}
 
-	handler := serve(configureHandler(api, useOpts.strictMiddlewares))
-	for _, middleware := range useOpts.lowLevelMiddlewares {
-		handler = middleware(handler)
+// ServeAPIWithOptions is ServeAPI for configureHandler functions that take
+// the StrictHTTPServerOptions oapi-codegen generates too, such as
+// NewStrictHandlerWithOptions, which it passes the APIRequestErrorHandler and
+// APIResponseErrorHandler options. By default, strict servers answer errors
+// with problems instead of plain text.
+func ServeAPIWithOptions[STRICTAPI any, API any, SERVEROPTIONS strictServerOptions](
+	api STRICTAPI,
+	configureHandler func(
+		STRICTAPI,
+		[]nethttp.StrictHTTPMiddlewareFunc,
+		SERVEROPTIONS,
+	) API,
+	serve func(API) http.Handler,
+	opts ...Option,
+) lambda.Handler {
+	useOpts := newOptions(opts)
+	serverOpts := SERVEROPTIONS{
+		RequestErrorHandlerFunc:  useOpts.requestErrors,
+		ResponseErrorHandlerFunc: useOpts.responseErrors,
 	}
-
-	return ServeHTTP(handler, useOpts.lowLevelMiddlewares...)
+	return ServeHTTP(serve(configureHandler(api, useOpts.strictMiddlewares, serverOpts)), opts...)
 }
 
 func ServeHTTP(
 	handler http.Handler,
-	middleware ...func(http.Handler) http.Handler,
+	opts ...Option,
 ) lambda.Handler {
-	for _, middleware := range middleware {
-		handler = middleware(handler)
-	}
+	b := newBridge(handler, opts)
 
-	lambdaHandler := func(ctx context.Context, req json.RawMessage) (json.RawMessage, error) {
-		slog.Info("received request payload", "request.payload.raw", req)
-		lambdaHTTPResponseWriter := &lambdaHTTPResponseWriter{}
+	return b.lambdaHandler(func(ctx context.Context, req []byte) ([]byte, error) {
+		payloads := lambdalog.PayloadsFromContext(ctx)
+		payloads.Log(ctx, b.logger, "received request payload", "request.payload", req)
+		lambdaHTTPResponseWriter := b.newResponseWriter()
+		resp := &ambiguousLambdaResponse{}
 		disambiguatedRequest, err := demuxAmbiguousRequest(req, lambdaHTTPResponseWriter)
 		if err != nil {
-			slog.ErrorContext(ctx, "failed to demux ambiguous request", "error", err)
-			return json.Marshal(leastCommonDenominatorResponse{
-				StatusCode: http.StatusInternalServerError,
-				Body:       err.Error(),
-			})
-		}
-
-		httpRequest, err := disambiguatedRequest.Canonize(ctx)
-		if err != nil {
-			slog.ErrorContext(ctx, "failed to canonize request", "error", err)
-			return json.Marshal(leastCommonDenominatorResponse{
-				StatusCode: http.StatusInternalServerError,
-				Body:       err.Error(),
-			})
+			b.logger.ErrorContext(ctx, "failed to demux ambiguous request", "error", err)
+			err = b.fail(ctx, lambdaHTTPResponseWriter, resp, err)
+		} else {
+			err = b.roundTrip(ctx, disambiguatedRequest, lambdaHTTPResponseWriter, resp)
 		}
-		handler.ServeHTTP(lambdaHTTPResponseWriter, httpRequest)
-		resp := &ambiguousLambdaResponse{}
-		err = resp.TranscodeFrom(lambdaHTTPResponseWriter)
 		if err != nil {
-			slog.ErrorContext(ctx, "failed to transcode response", "error", err)
+			b.logger.ErrorContext(ctx, "failed to render error response", "error", err)
 			return json.Marshal(leastCommonDenominatorResponse{
 				StatusCode: http.StatusInternalServerError,
-				Body:       err.Error(),
 			})
 		}
-		slog.InfoContext(ctx, "wrote response in memory", "resp", resp.String(), "resp.writer", lambdaHTTPResponseWriter.String())
+		payloads.Log(ctx, b.logger, "wrote response payload", "response.payload", resp.bytes)
 		return resp.bytes, nil
-	}
-
-	return lambda.NewHandlerWithOptions(lambdaHandler, lambda.WithEnableSIGTERM(func() {
-		slog.Info("received SIGTERM, shutting down")
-	}))
+	})
 }
 
 func ServeAPIGatewayV2(
 	handler http.Handler,
-	middleware ...func(http.Handler) http.Handler,
+	opts ...Option,
 ) lambda.Handler {
 	return serve(
 		handler,
@@ -96,20 +101,17 @@ func ServeAPIGatewayV2(
 
 			return ptr(events.APIGatewayV2HTTPResponse(*res))
 		},
-		func() *apiGatewayV2Response { return &apiGatewayV2Response{} },
-		func(statusCode int, err error) *events.APIGatewayV2HTTPResponse {
-			return &events.APIGatewayV2HTTPResponse{
-				StatusCode: statusCode,
-				Body:       err.Error(),
-			}
+		func(*apiGatewayV2Request) *apiGatewayV2Response { return &apiGatewayV2Response{} },
+		func(statusCode int) *events.APIGatewayV2HTTPResponse {
+			return &events.APIGatewayV2HTTPResponse{StatusCode: statusCode}
 		},
-		middleware...,
+		opts...,
 	)
 }
 
 func ServeAPIGateway(
 	handler http.Handler,
-	middleware ...func(http.Handler) http.Handler,
+	opts ...Option,
 ) lambda.Handler {
 	return serve(
 		handler,
@@ -123,20 +125,17 @@ func ServeAPIGateway(
 
 			return ptr(events.APIGatewayProxyResponse(*res))
 		},
-		func() *apiGatewayV1Response { return &apiGatewayV1Response{} },
-		func(statusCode int, err error) *events.APIGatewayProxyResponse {
-			return &events.APIGatewayProxyResponse{
-				StatusCode: statusCode,
-				Body:       err.Error(),
-			}
+		func(*apiGatewayV1Request) *apiGatewayV1Response { return &apiGatewayV1Response{} },
+		func(statusCode int) *events.APIGatewayProxyResponse {
+			return &events.APIGatewayProxyResponse{StatusCode: statusCode}
 		},
-		middleware...,
+		opts...,
 	)
 }
 
 func ServeALB(
 	handler http.Handler,
-	middleware ...func(http.Handler) http.Handler,
+	opts ...Option,
 ) lambda.Handler {
 	return serve(
 		handler,
@@ -150,14 +149,11 @@ func ServeALB(
 
 			return ptr(events.ALBTargetGroupResponse(*res))
 		},
-		func() *albResponse { return &albResponse{} },
-		func(statusCode int, err error) *events.ALBTargetGroupResponse {
-			return &events.ALBTargetGroupResponse{
-				StatusCode: statusCode,
-				Body:       err.Error(),
-			}
+		(*albRequest).newResponse,
+		func(statusCode int) *events.ALBTargetGroupResponse {
+			return &events.ALBTargetGroupResponse{StatusCode: statusCode}
 		},
-		middleware...,
+		opts...,
 	)
 }
 
@@ -165,37 +161,199 @@ func serve[RAWREQ any, REQ lambdaHTTPRequest, RAWRESP any, RESP lambdaHTTPRespon
 	handler http.Handler,
 	castReq func(RAWREQ) REQ,
 	castResp func(RESP) RAWRESP,
-	newResp func() RESP,
-	newErrResp func(int, error) RAWRESP,
-	middleware ...func(http.Handler) http.Handler,
+	newResp func(REQ) RESP,
+	newErrResp func(int) RAWRESP,
+	opts ...Option,
 ) lambda.Handler {
-	for _, middleware := range middleware {
-		handler = middleware(handler)
-	}
+	b := newBridge(handler, opts)
 
-	lambdaHandler := func(ctx context.Context, rawReq RAWREQ) (RAWRESP, error) {
-		slog.InfoContext(ctx, "received request payload", slog.Group("request", "payload", rawReq))
-		lambdaHTTPResponseWriter := &lambdaHTTPResponseWriter{}
+	return b.lambdaHandler(func(ctx context.Context, payload []byte) ([]byte, error) {
+		payloads := lambdalog.PayloadsFromContext(ctx)
+		payloads.Log(ctx, b.logger, "received request payload", "request.payload", payload)
+		var rawReq RAWREQ
+		if err := json.Unmarshal(payload, &rawReq); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request payload: %w", err)
+		}
+		lambdaHTTPResponseWriter := b.newResponseWriter()
 		req := castReq(rawReq)
-		httpRequest, err := req.Canonize(ctx)
-		if err != nil {
-			slog.ErrorContext(ctx, "failed to canonize request", "error", err)
-			return newErrResp(http.StatusInternalServerError, err), nil
+		resp := newResp(req)
+		if err := b.roundTrip(ctx, req, lambdaHTTPResponseWriter, resp); err != nil {
+			b.logger.ErrorContext(ctx, "failed to render error response", "error", err)
+			return marshalResponse(newErrResp(http.StatusInternalServerError))
 		}
-		handler.ServeHTTP(lambdaHTTPResponseWriter, httpRequest)
-		resp := newResp()
-		err = resp.TranscodeFrom(lambdaHTTPResponseWriter)
+		out, err := marshalResponse(castResp(resp))
 		if err != nil {
-			slog.ErrorContext(ctx, "failed to transcode response", "error", err)
-			return newErrResp(http.StatusInternalServerError, err), nil
+			return nil, err
+		}
+		payloads.Log(ctx, b.logger, "wrote response payload", "response.payload", out)
+		return out, nil
+	})
+}
+
+// marshalResponse encodes like the Lambda runtime would, without escaping
+// HTML in response bodies.
+func marshalResponse(resp any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(resp); err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// bridge is the part of an invocation shared by every event source: turning
+// the event into an *http.Request, serving it, and turning what was written
+// back into an event response.
+type bridge struct {
+	handler            http.Handler
+	errorRenderer      ErrorRenderer
+	binaryContentTypes []string
+	logger             *slog.Logger
+	lambdaMiddlewares  []func(lambda.Handler) lambda.Handler
+	lambdaOptions      []lambda.Option
+	onPanic            func(ctx context.Context, recovered any, stack []byte)
+	deadlineMargin     time.Duration
+	payloads           lambdalog.Payloads
+	maxResponseSize    int // 0 for the event source's own
+	oversizePolicy     OversizePolicy
+}
+
+func newBridge(handler http.Handler, opts []Option) *bridge {
+	useOpts := newOptions(opts)
+	if useOpts.docs != nil && useOpts.spec == nil {
+		panic("httpbridge: ServeDocsUI needs ServeOpenAPI")
+	}
+	if useOpts.spec != nil {
+		handler = serveSpec(handler, useOpts.spec, useOpts.docs, useOpts.errorRenderer)
+	}
+	for _, middleware := range useOpts.lowLevelMiddlewares {
+		handler = middleware(handler)
+	}
+	lambdaMiddlewares := useOpts.lambdaMiddlewares
+	if useOpts.tracing != nil {
+		tracing := append([]lambdaotel.Option{
+			lambdaotel.Route(Route),
+			lambdaotel.Trigger(semconv.FaaSTriggerHTTP),
+		}, useOpts.tracing...)
+		handler = lambdaotel.HTTP(tracing...)(handler)
+		lambdaMiddlewares = append(lambdaMiddlewares, lambdaotel.Invocation(tracing...))
+	}
+	// outermost, so every invocation counts whether or not it gets far
+	// enough to ask IsColdStart
+	lambdaMiddlewares = append(lambdaMiddlewares, lambdamiddleware.ColdStart())
+	// the bridge's own rewrites happen before any middleware sees the request
+	if useOpts.basePath != nil {
+		handler = StripBasePath(*useOpts.basePath)(handler)
+	}
+	if useOpts.trustedProxies != nil {
+		handler = ClientIP(*useOpts.trustedProxies)(handler)
+	}
+
+	logger := useOpts.logger
+	if logger == nil {
+		logger = lambdalog.Default()
+	}
+	logger = slog.New(lambdalog.NewHandler(logger.Handler()))
+	return &bridge{
+		handler:            handler,
+		errorRenderer:      useOpts.errorRenderer,
+		binaryContentTypes: useOpts.binaryContentTypes,
+		logger:             logger,
+		lambdaMiddlewares:  lambdaMiddlewares,
+		lambdaOptions:      useOpts.lambdaOptions,
+		onPanic:            useOpts.onPanic,
+		deadlineMargin:     useOpts.deadlineMargin,
+		payloads:           useOpts.payloads,
+		maxResponseSize:    useOpts.maxResponseSize,
+		oversizePolicy:     useOpts.oversizePolicy,
+	}
+}
+
+// lambdaHandler wraps invoke in the lambda middlewares, and those in a single
+// handler carrying the lambda options, since the runtime only honours the
+// options of the outermost one. Everything inside logs with b.logger and
+// b.payloads through lambdalog.FromContext and lambdalog.PayloadsFromContext.
+func (b *bridge) lambdaHandler(invoke lambdamiddleware.HandlerFunc) lambda.Handler {
+	wrapped := lambdamiddleware.Wrap(invoke, b.lambdaMiddlewares...)
+	handler := lambdamiddleware.HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		ctx = lambdalog.NewContext(ctx, b.logger)
+		ctx = lambdalog.WithPayloads(ctx, b.payloads)
+		return wrapped.Invoke(ctx, payload)
+	})
+
+	lambdaOptions := append([]lambda.Option{
+		lambda.WithEnableSIGTERM(func() {
+			b.logger.Info("received SIGTERM, shutting down")
+		}),
+	}, b.lambdaOptions...)
+	return lambda.NewHandlerWithOptions(handler, lambdaOptions...)
+}
+
+func (b *bridge) newResponseWriter() *lambdaHTTPResponseWriter {
+	return &lambdaHTTPResponseWriter{binaryContentTypes: b.binaryContentTypes}
+}
+
+func (b *bridge) roundTrip(
+	ctx context.Context,
+	req lambdaHTTPRequest,
+	w *lambdaHTTPResponseWriter,
+	resp lambdaHTTPResponse,
+) error {
+	httpRequest, err := req.Canonize(ctx)
+	if err != nil {
+		b.logger.ErrorContext(ctx, "failed to canonize request", "error", err)
+		return b.fail(ctx, w, resp, err)
+	}
+	if err := b.serve(ctx, w, httpRequest); err != nil {
+		return b.fail(ctx, w, resp, err)
+	}
+	if err := b.limitResponse(ctx, req, w); err != nil {
+		return b.fail(ctx, w, resp, err)
+	}
+	if err := resp.TranscodeFrom(w); err != nil {
+		b.logger.ErrorContext(ctx, "failed to transcode response", "error", err)
+		return b.fail(ctx, w, resp, err)
+	}
+	return nil
+}
+
+// serveHTTP recovers from panics in the handler, so they can be answered
+// like any other failure instead of taking the execution environment down.
+func (b *bridge) serveHTTP(ctx context.Context, w *lambdaHTTPResponseWriter, r *http.Request) error {
+	var recovered any
+	var stack []byte
+	func() {
+		defer func() {
+			if recovered = recover(); recovered != nil {
+				stack = debug.Stack()
+			}
+		}()
+		b.handler.ServeHTTP(w, r)
+	}()
+	if recovered == nil {
+		return nil
+	}
+
+	// like net/http, don't make noise about deliberate aborts
+	if recovered != http.ErrAbortHandler { //nolint:errorlint // compared like net/http does
+		b.logger.ErrorContext(ctx, "recovered from panic in http handler",
+			"panic", fmt.Sprint(recovered), "stack", string(stack))
+		if b.onPanic != nil {
+			b.onPanic(ctx, recovered, stack)
 		}
-		slog.InfoContext(ctx, "wrote response in memory", "resp", resp, "resp.writer", lambdaHTTPResponseWriter)
-		return castResp(resp), nil
 	}
+	return fmt.Errorf("%w: %v", lambdamiddleware.ErrPanic, recovered)
+}
 
-	return lambda.NewHandlerWithOptions(lambdaHandler, lambda.WithEnableSIGTERM(func() {
-		slog.Info("received SIGTERM, shutting down")
-	}))
+// fail replaces whatever was written to w with the rendering of err.
+func (b *bridge) fail(ctx context.Context, w *lambdaHTTPResponseWriter, resp lambdaHTTPResponse, err error) error {
+	w.reset()
+	b.errorRenderer(ctx, w, errorStatus(err), err)
+	if err := resp.TranscodeFrom(w); err != nil {
+		return fmt.Errorf("failed to transcode error response: %w", err)
+	}
+	return nil
 }
 
 func ptr[TO any](to TO) *TO {
@@ -1,16 +1,41 @@
 package httpbridge_test
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oapi-codegen/runtime/strictmiddleware/nethttp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 
 	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/httpbridge/httpbridgetest"
+	"github.com/geode-io/golambdas/lambdalog"
+	"github.com/geode-io/golambdas/lambdamiddleware"
+	"github.com/geode-io/golambdas/lambdaotel"
 )
 
 func Test_ServeHTTP(t *testing.T) {
@@ -60,9 +85,1239 @@ func Test_ServeHTTP(t *testing.T) {
 	}
 }
 
+func Test_ServeHTTP_Addressing(t *testing.T) {
+	tests := []struct {
+		name           string
+		reqJSON        string
+		wantScheme     string
+		wantHost       string
+		wantRemoteAddr string
+	}{
+		{
+			name:           "API Gateway - REST",
+			reqJSON:        apiGatewayHelloWorldRequest,
+			wantScheme:     "https",
+			wantHost:       "0123456789.execute-api.us-east-1.amazonaws.com",
+			wantRemoteAddr: "127.0.0.1:0",
+		},
+		{
+			name:           "API Gateway - HTTP",
+			reqJSON:        apiGatewayHTTPHelloWorldRequest,
+			wantScheme:     "https",
+			wantHost:       "id.execute-api.us-east-1.amazonaws.com",
+			wantRemoteAddr: "192.0.2.1:0",
+		},
+		{
+			name:           "ALB Target Group",
+			reqJSON:        albTargetGroupHelloWorldRequest,
+			wantScheme:     "https",
+			wantHost:       "lambda-846800462-us-east-2.elb.amazonaws.com",
+			wantRemoteAddr: "72.21.198.66:0",
+		},
+		{
+			name: "ALB Target Group - single value headers over plain HTTP",
+			reqJSON: `{
+				"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:region:123456789012:targetgroup/tg/1"}},
+				"httpMethod": "GET",
+				"path": "/",
+				"headers": {
+					"host": "internal.example.com",
+					"x-forwarded-for": "203.0.113.7, 10.0.0.1",
+					"x-forwarded-port": "8080",
+					"x-forwarded-proto": "http"
+				}
+			}`,
+			wantScheme:     "http",
+			wantHost:       "internal.example.com:8080",
+			wantRemoteAddr: "10.0.0.1:0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := captureRequest(t, tt.reqJSON)
+			assert.Equal(t, tt.wantScheme, r.URL.Scheme)
+			assert.Equal(t, tt.wantHost, r.URL.Host)
+			assert.Equal(t, tt.wantHost, r.Host)
+			assert.Equal(t, tt.wantRemoteAddr, r.RemoteAddr)
+		})
+	}
+}
+
+func Test_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy httpbridge.TrustedProxies
+		peer   string
+		chain  []string
+		want   string
+	}{
+		{
+			name:  "trust nothing returns the peer",
+			peer:  "10.0.0.1",
+			chain: []string{"203.0.113.7"},
+			want:  "10.0.0.1",
+		},
+		{
+			name:  "trust nothing without a peer returns the last hop",
+			chain: []string{"203.0.113.7", "10.0.0.1"},
+			want:  "10.0.0.1",
+		},
+		{
+			name:   "hop count skips proxies in front of the event source",
+			policy: httpbridge.TrustedProxies{Hops: 1},
+			peer:   "10.0.0.1",
+			chain:  []string{"198.51.100.1", "203.0.113.7", "10.0.0.1"},
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "prefixes skip every trusted hop",
+			policy: httpbridge.TrustedProxies{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+			peer:   "10.0.0.1",
+			chain:  []string{"198.51.100.1", "203.0.113.7", "10.2.0.1"},
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "all trusted returns the leftmost hop",
+			policy: httpbridge.TrustedProxies{Hops: 5},
+			chain:  []string{"[2001:db8::1]:443", "10.2.0.1"},
+			want:   "2001:db8::1",
+		},
+		{
+			name:   "garbage stops the walk",
+			policy: httpbridge.TrustedProxies{Hops: 5},
+			chain:  []string{"203.0.113.7", "unknown", "10.2.0.1"},
+			want:   "10.2.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Resolve(tt.peer, tt.chain))
+		})
+	}
+}
+
+func Test_ClientIP(t *testing.T) {
+	var got string
+	handler := httpbridge.ClientIP(httpbridge.TrustedProxies{Hops: 1})(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got = r.RemoteAddr
+		}),
+	)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "127.0.0.2:0"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 127.0.0.2")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "198.51.100.1:0", got)
+}
+
+func Test_StripBasePath(t *testing.T) {
+	tests := []struct {
+		name         string
+		basePath     string
+		reqJSON      string
+		wantPath     string
+		wantBasePath string
+	}{
+		{
+			name: "API Gateway - REST - derived from resource",
+			reqJSON: `{
+				"resource": "/users/{id}",
+				"path": "/v1/users/42",
+				"httpMethod": "GET",
+				"pathParameters": {"id": "42"},
+				"requestContext": {"accountId": "123456789012", "stage": "prod", "path": "/v1/users/42"}
+			}`,
+			wantPath:     "/users/42",
+			wantBasePath: "/v1",
+		},
+		{
+			name:         "API Gateway - REST - greedy proxy resource",
+			reqJSON:      apiGatewayHelloWorldRequest,
+			wantPath:     "/path/to/resource",
+			wantBasePath: "",
+		},
+		{
+			name: "API Gateway - HTTP - named stage",
+			reqJSON: `{
+				"version": "2.0",
+				"routeKey": "$default",
+				"rawPath": "/prod/my/path",
+				"requestContext": {"accountId": "123456789012", "stage": "prod", "http": {"method": "GET"}}
+			}`,
+			wantPath:     "/my/path",
+			wantBasePath: "/prod",
+		},
+		{
+			name:     "API Gateway - HTTP - configured base path",
+			basePath: "/api/",
+			reqJSON: `{
+				"version": "2.0",
+				"routeKey": "$default",
+				"rawPath": "/api/my/path",
+				"requestContext": {"accountId": "123456789012", "stage": "$default", "http": {"method": "GET"}}
+			}`,
+			wantPath:     "/my/path",
+			wantBasePath: "/api",
+		},
+		{
+			name:     "API Gateway - HTTP - base path only on segment boundary",
+			basePath: "api",
+			reqJSON: `{
+				"version": "2.0",
+				"routeKey": "$default",
+				"rawPath": "/apis/my/path",
+				"requestContext": {"accountId": "123456789012", "stage": "$default", "http": {"method": "GET"}}
+			}`,
+			wantPath:     "/apis/my/path",
+			wantBasePath: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := captureRequest(t, tt.reqJSON, httpbridge.HTTPMiddleware(httpbridge.StripBasePath(tt.basePath)))
+			assert.Equal(t, tt.wantPath, r.URL.Path)
+			assert.Equal(t, tt.wantBasePath, httpbridge.BasePathFromContext(r.Context()))
+		})
+	}
+}
+
+func Test_ServeHTTP_EscapedPaths(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		wantPath        string
+		wantEscapedPath string
+	}{
+		{name: "plain", path: "/items/42", wantPath: "/items/42", wantEscapedPath: "/items/42"},
+		{name: "encoded slash", path: "/items/a%2Fb", wantPath: "/items/a/b", wantEscapedPath: "/items/a%2Fb"},
+		{name: "encoded space", path: "/items/a%20b", wantPath: "/items/a b", wantEscapedPath: "/items/a%20b"},
+		{name: "unicode", path: "/items/caf%C3%A9", wantPath: "/items/café", wantEscapedPath: "/items/caf%C3%A9"},
+		{name: "lowercase escapes", path: "/items/caf%c3%a9", wantPath: "/items/café", wantEscapedPath: "/items/caf%c3%a9"},
+		{name: "plus sign", path: "/items/a+b", wantPath: "/items/a+b", wantEscapedPath: "/items/a+b"},
+		{name: "encoded plus sign", path: "/items/a%2Bb", wantPath: "/items/a+b", wantEscapedPath: "/items/a%2Bb"},
+		{name: "empty", path: "", wantPath: "/", wantEscapedPath: "/"},
+	}
+
+	for _, source := range eventSources {
+		for _, tt := range tests {
+			t.Run(source.name+" - "+tt.name, func(t *testing.T) {
+				r := captureRequest(t, source.withPath(t, tt.path, "q=1"))
+				assert.Equal(t, tt.wantPath, r.URL.Path)
+				assert.Equal(t, tt.wantEscapedPath, r.URL.EscapedPath())
+				assert.Equal(t, tt.wantEscapedPath+"?q=1", r.RequestURI)
+			})
+		}
+
+		t.Run(source.name+" - malformed escape", func(t *testing.T) {
+			resp, err := httpbridge.ServeHTTP(http.NotFoundHandler()).
+				Invoke(context.Background(), []byte(source.withPath(t, "/items/%zz", "")))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, statusCodeOf(t, resp))
+		})
+	}
+}
+
+func Test_ServeHTTP_QueryStrings(t *testing.T) {
+	wantQuery := url.Values{
+		"tag":   {"b", "a"},
+		"q":     {"café au lait"},
+		"plus":  {"+"},
+		"empty": {""},
+	}
+	tests := []struct {
+		name         string
+		reqJSON      string
+		wantRawQuery string
+		wantQuery    url.Values
+	}{
+		{
+			name:         "API Gateway - REST",
+			reqJSON:      apiGatewayQueryRequest,
+			wantRawQuery: "empty=&plus=%2B&q=caf%C3%A9+au+lait&tag=b&tag=a",
+			wantQuery:    wantQuery,
+		},
+		{
+			name:         "API Gateway - HTTP",
+			reqJSON:      apiGatewayHTTPQueryRequest,
+			wantRawQuery: "tag=b&q=caf%C3%A9+au+lait&tag=a&plus=%2B&empty=",
+			wantQuery:    wantQuery,
+		},
+		{
+			name:         "ALB Target Group - multi value",
+			reqJSON:      albMultiValueQueryRequest,
+			wantRawQuery: "empty=&plus=%2B&q=caf%C3%A9+au+lait&tag=b&tag=a",
+			wantQuery:    wantQuery,
+		},
+		{
+			name:         "ALB Target Group - single value",
+			reqJSON:      albSingleValueQueryRequest,
+			wantRawQuery: "empty=&plus=%2B&q=caf%C3%A9+au+lait&tag=a",
+			wantQuery: url.Values{
+				"tag":   {"a"},
+				"q":     {"café au lait"},
+				"plus":  {"+"},
+				"empty": {""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := captureRequest(t, tt.reqJSON)
+			assert.Equal(t, tt.wantRawQuery, r.URL.RawQuery)
+			assert.Equal(t, tt.wantQuery, r.URL.Query())
+			assert.Equal(t, "/search?"+tt.wantRawQuery, r.RequestURI)
+		})
+	}
+}
+
+func Test_ServeHTTP_Cookies(t *testing.T) {
+	cookies := []string{"a=1; Path=/", "b=2; HttpOnly", "c=3; Secure"}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for _, cookie := range cookies {
+			w.Header().Add("Set-Cookie", cookie)
+		}
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Cookie")
+		w.WriteHeader(http.StatusOK)
+	})
+	invoke := func(t *testing.T, reqJSON string, out any) {
+		t.Helper()
+		resp, err := httpbridge.ServeHTTP(handler).Invoke(context.Background(), []byte(reqJSON))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(resp, out))
+	}
+
+	t.Run("API Gateway - REST", func(t *testing.T) {
+		var resp events.APIGatewayProxyResponse
+		invoke(t, apiGatewayHelloWorldRequest, &resp)
+		assert.Equal(t, cookies, resp.MultiValueHeaders["Set-Cookie"])
+		assert.Equal(t, []string{"Accept", "Cookie"}, resp.MultiValueHeaders["Vary"])
+	})
+
+	t.Run("API Gateway - HTTP", func(t *testing.T) {
+		var resp events.APIGatewayV2HTTPResponse
+		invoke(t, apiGatewayHTTPHelloWorldRequest, &resp)
+		assert.Equal(t, cookies, resp.Cookies)
+		assert.NotContains(t, resp.Headers, "Set-Cookie")
+	})
+
+	t.Run("ALB Target Group - multi value headers", func(t *testing.T) {
+		var resp events.ALBTargetGroupResponse
+		invoke(t, albTargetGroupHelloWorldRequest, &resp)
+		assert.Equal(t, cookies, resp.MultiValueHeaders["Set-Cookie"])
+		assert.Equal(t, []string{"Accept", "Cookie"}, resp.MultiValueHeaders["Vary"])
+		assert.Empty(t, resp.Headers)
+	})
+
+	t.Run("ALB Target Group - single value headers", func(t *testing.T) {
+		var resp events.ALBTargetGroupResponse
+		invoke(t, albSingleValueQueryRequest, &resp)
+		assert.Nil(t, resp.MultiValueHeaders)
+		assert.Equal(t, "Accept, Cookie", resp.Headers["Vary"])
+
+		var got []string
+		for k, v := range resp.Headers {
+			if strings.EqualFold(k, "Set-Cookie") {
+				got = append(got, v)
+			}
+		}
+		assert.ElementsMatch(t, cookies, got)
+	})
+
+	t.Run("API Gateway - HTTP - request cookies", func(t *testing.T) {
+		r := captureRequest(t, apiGatewayHTTPHelloWorldRequest)
+		assert.Equal(t, "cookie1; cookie2", r.Header.Get("Cookie"))
+	})
+}
+
+func Test_ServeHTTP_ErrorRendering(t *testing.T) {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID: "8f5c0e0c-2d43-4b43-a3f6-0c8e3b7a1d2e",
+	})
+	badBody := `{
+		"version": "2.0",
+		"rawPath": "/items",
+		"body": "not base64!",
+		"isBase64Encoded": true,
+		"requestContext": {"http": {"method": "POST"}}
+	}`
+
+	t.Run("default renders problem details", func(t *testing.T) {
+		resp, err := httpbridge.ServeHTTP(http.NotFoundHandler()).Invoke(ctx, []byte(badBody))
+		require.NoError(t, err)
+
+		var out events.APIGatewayV2HTTPResponse
+		require.NoError(t, json.Unmarshal(resp, &out))
+		assert.Equal(t, http.StatusBadRequest, out.StatusCode)
+		assert.Equal(t, "application/problem+json", out.Headers["Content-Type"])
+		assert.JSONEq(t, `{
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "malformed request body",
+			"requestId": "8f5c0e0c-2d43-4b43-a3f6-0c8e3b7a1d2e"
+		}`, out.Body)
+	})
+
+	t.Run("unsupported events are server errors", func(t *testing.T) {
+		resp, err := httpbridge.ServeHTTP(http.NotFoundHandler()).Invoke(ctx, []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, statusCodeOf(t, resp))
+		assert.NotContains(t, string(resp), httpbridge.ErrUnsupportedRequestType.Error())
+	})
+
+	t.Run("custom renderer", func(t *testing.T) {
+		var gotErr error
+		renderer := func(_ context.Context, w http.ResponseWriter, statusCode int, err error) {
+			gotErr = err
+			w.WriteHeader(statusCode + 1)
+		}
+		event := eventSources[0].withPath(t, "/items/%zz", "")
+		resp, err := httpbridge.ServeAPIGateway(http.NotFoundHandler(), httpbridge.ErrorRendering(renderer)).
+			Invoke(ctx, []byte(event))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest+1, statusCodeOf(t, resp))
+		assert.ErrorContains(t, gotErr, "%zz")
+	})
+}
+
+func Test_ServeHTTP_Options(t *testing.T) {
+	t.Run("lambda middleware wraps the raw invocation", func(t *testing.T) {
+		var calls []string
+		middleware := func(name string) func(lambda.Handler) lambda.Handler {
+			return func(next lambda.Handler) lambda.Handler {
+				return lambdamiddleware.HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+					calls = append(calls, name)
+					return next.Invoke(ctx, payload)
+				})
+			}
+		}
+		handler := httpbridge.ServeALB(
+			http.NotFoundHandler(),
+			httpbridge.LambdaMiddleware(middleware("inner"), middleware("outer")),
+		)
+		resp, err := handler.Invoke(context.Background(), []byte(albTargetGroupHelloWorldRequest))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, statusCodeOf(t, resp))
+		assert.Equal(t, []string{"outer", "inner"}, calls)
+	})
+
+	t.Run("ServeAPI applies HTTP middleware once", func(t *testing.T) {
+		calls := 0
+		counting := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				next.ServeHTTP(w, r)
+			})
+		}
+		handler := httpbridge.ServeAPI(
+			http.NotFoundHandler(),
+			func(api http.Handler, _ []nethttp.StrictHTTPMiddlewareFunc) http.Handler { return api },
+			func(api http.Handler) http.Handler { return api },
+			httpbridge.HTTPMiddleware(counting),
+		)
+		_, err := handler.Invoke(context.Background(), []byte(apiGatewayHelloWorldRequest))
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("binary content types", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			contentType string
+			body        []byte
+			wantBase64  bool
+		}{
+			{name: "json", contentType: "application/json", body: []byte(`{"a":1}`)},
+			{name: "octet stream", contentType: "application/octet-stream", body: []byte("abc"), wantBase64: true},
+			{name: "configured wildcard", contentType: "image/png", body: []byte("abc"), wantBase64: true},
+			{name: "invalid utf-8", contentType: "text/plain", body: []byte{0xff, 0xfe}, wantBase64: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Content-Type", tt.contentType)
+					_, _ = w.Write(tt.body)
+				})
+				resp, err := httpbridge.ServeAPIGatewayV2(handler, httpbridge.BinaryContentTypes("image/*")).
+					Invoke(context.Background(), []byte(apiGatewayHTTPHelloWorldRequest))
+				require.NoError(t, err)
+
+				var out events.APIGatewayV2HTTPResponse
+				require.NoError(t, json.Unmarshal(resp, &out))
+				assert.Equal(t, tt.wantBase64, out.IsBase64Encoded)
+				body := []byte(out.Body)
+				if out.IsBase64Encoded {
+					body, err = base64.StdEncoding.DecodeString(out.Body)
+					require.NoError(t, err)
+				}
+				assert.Equal(t, tt.body, body)
+			})
+		}
+	})
+
+	t.Run("handlers log with the bridge's logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			lambdalog.FromContext(r.Context()).InfoContext(r.Context(), "from the handler")
+		})
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+			AwsRequestID: "c1a4f3de-6b8e-4f0e-9d1b-3e5f7a9b2c4d",
+		})
+		_, err := httpbridge.ServeALB(handler, httpbridge.Logger(logger)).
+			Invoke(ctx, []byte(albTargetGroupHelloWorldRequest))
+		require.NoError(t, err)
+
+		var found bool
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			assert.Equal(t, "c1a4f3de-6b8e-4f0e-9d1b-3e5f7a9b2c4d", record["requestId"])
+			found = found || record["msg"] == "from the handler"
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("the default logger follows the logging configuration", func(t *testing.T) {
+		t.Setenv("AWS_LAMBDA_LOG_FORMAT", "JSON")
+		t.Setenv("AWS_LAMBDA_LOG_LEVEL", "WARN")
+		stdout := os.Stdout
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stdout = w
+		defer func() { os.Stdout = stdout }()
+
+		handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			lambdalog.FromContext(r.Context()).InfoContext(r.Context(), "hidden")
+			lambdalog.FromContext(r.Context()).WarnContext(r.Context(), "shown")
+		})
+		_, err = httpbridge.ServeALB(handler).Invoke(context.Background(), []byte(albTargetGroupHelloWorldRequest))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		require.Len(t, lines, 1, string(out))
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+		assert.Equal(t, "shown", record["message"])
+		assert.Equal(t, "WARN", record["level"])
+		assert.Contains(t, record, "timestamp")
+	})
+
+	t.Run("a logger installed with slog.SetDefault is used", func(t *testing.T) {
+		var buf bytes.Buffer
+		previous := slog.Default()
+		slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+		defer slog.SetDefault(previous)
+
+		_, err := httpbridge.ServeALB(http.NotFoundHandler()).Invoke(context.Background(), []byte(`{}`))
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), `"msg":"received request payload"`)
+	})
+
+	t.Run("payload logging", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			payloads lambdalog.Payloads
+			wantLogs bool
+		}{
+			{name: "default", wantLogs: true},
+			{name: "disabled", payloads: lambdalog.Payloads{Disabled: true}},
+			{name: "below the logger's level", payloads: lambdalog.Payloads{Level: slog.LevelDebug}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				logger := slog.New(slog.NewJSONHandler(&buf, nil))
+				handler := httpbridge.ServeHTTP(http.NotFoundHandler(),
+					httpbridge.Logger(logger),
+					httpbridge.PayloadLogging(tt.payloads),
+				)
+				_, err := handler.Invoke(context.Background(), []byte(apiGatewayHelloWorldRequest))
+				require.NoError(t, err)
+
+				if !tt.wantLogs {
+					assert.NotContains(t, buf.String(), "payload")
+					return
+				}
+				assert.Contains(t, buf.String(), `"msg":"received request payload"`)
+				assert.Contains(t, buf.String(), `"msg":"wrote response payload"`)
+			})
+		}
+	})
+
+	t.Run("tracing", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+		defer func() { _ = tracerProvider.Shutdown(context.Background()) }()
+
+		handler := httpbridge.ServeAPIGateway(http.NotFoundHandler(),
+			httpbridge.Tracing(lambdaotel.TracerProvider(tracerProvider)),
+		)
+		_, err := handler.Invoke(context.Background(), []byte(apiGatewayHelloWorldRequest))
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "POST /{proxy+}", spans[0].Name)
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+		assert.Contains(t, spans[1].Attributes, semconv.FaaSTriggerHTTP)
+	})
+
+	t.Run("trusted proxies and base path", func(t *testing.T) {
+		r := captureRequest(t, `{
+				"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:region:123456789012:targetgroup/tg/1"}},
+				"httpMethod": "GET",
+				"path": "/api/items",
+				"headers": {"x-forwarded-for": "203.0.113.7, 10.0.0.1"}
+			}`,
+			httpbridge.TrustProxies(httpbridge.TrustedProxies{Hops: 1}),
+			httpbridge.BasePath("api"),
+		)
+		assert.Equal(t, "/items", r.URL.Path)
+		assert.Equal(t, "203.0.113.7:0", r.RemoteAddr)
+	})
+}
+
+func Test_ServeHTTP_Panics(t *testing.T) {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID: "0d4e6a4e-41f1-4b5a-8f55-1d3d5c9c7a10",
+	})
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Partial", "true")
+		_, _ = w.Write([]byte("half a response"))
+		panic("boom")
+	})
+
+	tests := []struct {
+		name    string
+		handler func(http.Handler, ...httpbridge.Option) lambda.Handler
+		reqJSON string
+	}{
+		{name: "ServeHTTP", handler: httpbridge.ServeHTTP, reqJSON: apiGatewayHTTPHelloWorldRequest},
+		{name: "ServeAPIGateway", handler: httpbridge.ServeAPIGateway, reqJSON: apiGatewayHelloWorldRequest},
+		{name: "ServeAPIGatewayV2", handler: httpbridge.ServeAPIGatewayV2, reqJSON: apiGatewayHTTPHelloWorldRequest},
+		{name: "ServeALB", handler: httpbridge.ServeALB, reqJSON: albTargetGroupHelloWorldRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRecovered any
+			handler := tt.handler(panicking, httpbridge.OnPanic(func(_ context.Context, recovered any, _ []byte) {
+				gotRecovered = recovered
+			}))
+			resp, err := handler.Invoke(ctx, []byte(tt.reqJSON))
+			require.NoError(t, err)
+
+			var out struct {
+				StatusCode        int                 `json:"statusCode"`
+				Headers           map[string]string   `json:"headers"`
+				MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+				Body              string              `json:"body"`
+			}
+			require.NoError(t, json.Unmarshal(resp, &out))
+			assert.Equal(t, http.StatusInternalServerError, out.StatusCode)
+			assert.NotContains(t, out.Headers, "X-Partial")
+			assert.NotContains(t, out.MultiValueHeaders, "X-Partial")
+			assert.JSONEq(t, `{
+				"title": "Internal Server Error",
+				"status": 500,
+				"requestId": "0d4e6a4e-41f1-4b5a-8f55-1d3d5c9c7a10"
+			}`, out.Body)
+			assert.Equal(t, "boom", gotRecovered)
+		})
+	}
+}
+
+func Test_ServeHTTP_Deadlines(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			w.Header().Set("X-Late", "true")
+			_, _ = w.Write([]byte("too late"))
+		case <-time.After(50 * time.Millisecond):
+			_, _ = w.Write([]byte("on time"))
+		}
+	})
+
+	tests := []struct {
+		name       string
+		budget     time.Duration
+		opts       []httpbridge.Option
+		wantStatus int
+	}{
+		{name: "no margin", budget: time.Second, wantStatus: http.StatusOK},
+		{
+			name:       "enough time",
+			budget:     time.Second,
+			opts:       []httpbridge.Option{httpbridge.DeadlineMargin(20 * time.Millisecond)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "handler overruns",
+			budget:     60 * time.Millisecond,
+			opts:       []httpbridge.Option{httpbridge.DeadlineMargin(50 * time.Millisecond)},
+			wantStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.budget)
+			defer cancel()
+
+			resp, err := httpbridge.ServeALB(slow, tt.opts...).Invoke(ctx, []byte(albTargetGroupHelloWorldRequest))
+			require.NoError(t, err)
+
+			var out events.ALBTargetGroupResponse
+			require.NoError(t, json.Unmarshal(resp, &out))
+			assert.Equal(t, tt.wantStatus, out.StatusCode)
+			assert.NotContains(t, out.Headers, "X-Late")
+			assert.NotEqual(t, "too late", out.Body)
+		})
+	}
+}
+
+func Test_ServeHTTP_OversizedResponses(t *testing.T) {
+	const limit = 4096
+	text := strings.Repeat("<p>all work and no play</p>\n", 1000)
+	binary := bytes.Repeat([]byte{0xff, 0x00, 0x7f}, 5000)
+	store := httpbridgetest.NewMemoryStore("https://objects.example.com/responses")
+	oversized := func(policy httpbridge.OversizePolicy) []httpbridge.Option {
+		return []httpbridge.Option{httpbridge.OversizedResponses(policy)}
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		encoding    string
+		opts        []httpbridge.Option
+		wantStatus  int
+	}{
+		{name: "small enough", body: []byte("hello"), wantStatus: http.StatusOK},
+		{name: "fails by default", body: []byte(text), wantStatus: http.StatusInternalServerError},
+		{
+			name:       "fails with 413",
+			body:       []byte(text),
+			opts:       oversized(httpbridge.FailOversized(http.StatusRequestEntityTooLarge)),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "truncates text",
+			body:       []byte(text),
+			opts:       oversized(httpbridge.TruncateOversized()),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "truncates binary",
+			body:        binary,
+			contentType: "application/octet-stream",
+			opts:        oversized(httpbridge.TruncateOversized()),
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "offloads",
+			body:       []byte(text),
+			opts:       oversized(httpbridge.OffloadOversized(store)),
+			wantStatus: http.StatusSeeOther,
+		},
+		{
+			name:        "offloads encoded",
+			body:        binary,
+			contentType: "text/html",
+			encoding:    "gzip",
+			opts:        oversized(httpbridge.OffloadOversized(store)),
+			wantStatus:  http.StatusSeeOther,
+		},
+	}
+
+	for _, source := range eventSources {
+		for _, tt := range tests {
+			t.Run(source.name+" - "+tt.name, func(t *testing.T) {
+				handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					if tt.contentType != "" {
+						w.Header().Set("Content-Type", tt.contentType)
+					}
+					if tt.encoding != "" {
+						w.Header().Set("Content-Encoding", tt.encoding)
+					}
+					http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+					_, _ = w.Write(tt.body)
+				})
+				opts := append([]httpbridge.Option{httpbridge.ResponseSizeLimit(limit)}, tt.opts...)
+				ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+					AwsRequestID: "oversized-request",
+				})
+
+				resp, err := httpbridge.ServeHTTP(handler, opts...).Invoke(ctx, []byte(source.withPath(t, "/", "")))
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(resp), limit)
+
+				var out events.APIGatewayProxyResponse
+				require.NoError(t, json.Unmarshal(resp, &out))
+				require.Equal(t, tt.wantStatus, out.StatusCode)
+				body := []byte(out.Body)
+				if out.IsBase64Encoded {
+					body, err = base64.StdEncoding.DecodeString(out.Body)
+					require.NoError(t, err)
+				}
+
+				switch tt.wantStatus {
+				case http.StatusOK:
+					assert.True(t, bytes.HasPrefix(tt.body, body))
+					assert.Greater(t, len(body), min(len(tt.body), limit/8)-1)
+				case http.StatusSeeOther:
+					assert.Empty(t, body)
+					assert.Equal(t, "https://objects.example.com/responses/oversized-request",
+						out.Headers["Location"]+strings.Join(out.MultiValueHeaders["Location"], ""))
+					object, ok := store.Get("oversized-request")
+					require.True(t, ok)
+					assert.Equal(t, tt.body, object.Body)
+					assert.Equal(t, tt.encoding, object.ContentEncoding)
+				default:
+					assert.Contains(t, out.Body, "response too large")
+				}
+			})
+		}
+	}
+
+	assert.Panics(t, func() { httpbridge.FailOversized(http.StatusOK) })
+
+	// ALBs take a sixth of what API Gateway does
+	for _, source := range eventSources {
+		t.Run(source.name+" - default limit", func(t *testing.T) {
+			body := strings.Repeat("a", httpbridge.MaxALBResponseSize)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(body))
+			})
+
+			resp, err := httpbridge.ServeHTTP(handler).Invoke(context.Background(), []byte(source.withPath(t, "/", "")))
+			require.NoError(t, err)
+
+			var out events.APIGatewayProxyResponse
+			require.NoError(t, json.Unmarshal(resp, &out))
+			if source.name == "ALB Target Group" {
+				assert.LessOrEqual(t, len(resp), httpbridge.MaxALBResponseSize)
+				assert.Equal(t, http.StatusInternalServerError, out.StatusCode)
+			} else {
+				assert.Equal(t, http.StatusOK, out.StatusCode)
+				assert.Equal(t, body, out.Body)
+			}
+		})
+	}
+}
+
+// strictHTTPServerOptions is what oapi-codegen generates for strict servers.
+type strictHTTPServerOptions struct {
+	RequestErrorHandlerFunc  func(w http.ResponseWriter, r *http.Request, err error)
+	ResponseErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func Test_ServeAPIWithOptions(t *testing.T) {
+	// stands in for a strict handler, failing to decode when requestErr is
+	// set, and otherwise with what the strict API returns
+	type strictAPI struct{ requestErr, responseErr error }
+	configure := func(
+		api strictAPI,
+		_ []nethttp.StrictHTTPMiddlewareFunc,
+		opts strictHTTPServerOptions,
+	) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case api.requestErr != nil:
+				opts.RequestErrorHandlerFunc(w, r, api.requestErr)
+			case api.responseErr != nil:
+				opts.ResponseErrorHandlerFunc(w, r, api.responseErr)
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		})
+	}
+	serve := func(api http.Handler) http.Handler { return api }
+	validationErr := &httpbridge.ValidationError{
+		Detail: "pet names must be unique",
+		Errors: []httpbridge.ProblemError{{Detail: "already taken", Pointer: "/name"}},
+	}
+
+	tests := []struct {
+		name        string
+		api         strictAPI
+		opts        []httpbridge.Option
+		wantStatus  int
+		wantProblem httpbridge.Problem
+	}{
+		{
+			name:       "no error",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:        "request error",
+			api:         strictAPI{requestErr: errors.New("can't decode JSON body: unexpected EOF")},
+			wantStatus:  http.StatusBadRequest,
+			wantProblem: httpbridge.Problem{Title: "Bad Request", Status: 400, Detail: "malformed request body"},
+		},
+		{
+			name:        "not found",
+			api:         strictAPI{responseErr: fmt.Errorf("pet 42: %w", httpbridge.ErrNotFound)},
+			wantStatus:  http.StatusNotFound,
+			wantProblem: httpbridge.Problem{Title: "Not Found", Status: 404},
+		},
+		{
+			name:        "conflict",
+			api:         strictAPI{responseErr: fmt.Errorf("pet 42: %w", httpbridge.ErrConflict)},
+			wantStatus:  http.StatusConflict,
+			wantProblem: httpbridge.Problem{Title: "Conflict", Status: 409},
+		},
+		{
+			name:       "validation",
+			api:        strictAPI{responseErr: fmt.Errorf("failed to add pet: %w", validationErr)},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantProblem: httpbridge.Problem{
+				Title:  "Unprocessable Entity",
+				Status: 422,
+				Detail: "pet names must be unique",
+				Errors: validationErr.Errors,
+			},
+		},
+		{
+			name:        "other errors are not exposed",
+			api:         strictAPI{responseErr: errors.New("dial tcp 10.0.0.1:5432: connection refused")},
+			wantStatus:  http.StatusInternalServerError,
+			wantProblem: httpbridge.Problem{Title: "Internal Server Error", Status: 500},
+		},
+		{
+			name: "custom handler",
+			api:  strictAPI{responseErr: httpbridge.ErrNotFound},
+			opts: []httpbridge.Option{httpbridge.APIResponseErrorHandler(
+				func(w http.ResponseWriter, _ *http.Request, _ error) {
+					w.WriteHeader(http.StatusGone)
+				},
+			)},
+			wantStatus: http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := httpbridge.ServeAPIWithOptions(tt.api, configure, serve, tt.opts...).
+				Invoke(context.Background(), []byte(apiGatewayHelloWorldRequest))
+			require.NoError(t, err)
+
+			var out events.APIGatewayProxyResponse
+			require.NoError(t, json.Unmarshal(resp, &out))
+			require.Equal(t, tt.wantStatus, out.StatusCode, out.Body)
+			if tt.wantProblem.Status == 0 {
+				return
+			}
+			assert.Equal(t, "application/problem+json", out.Headers["Content-Type"])
+			var problem httpbridge.Problem
+			require.NoError(t, json.Unmarshal([]byte(out.Body), &problem))
+			assert.Equal(t, tt.wantProblem, problem)
+		})
+	}
+}
+
+func Test_ServeHTTP_OpenAPI(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+servers: [{url: "https://{apiId}.execute-api.eu-west-1.amazonaws.com/{stage}", description: Production}]
+paths:
+  /pets:
+    get: {responses: {"200": {description: ok}}}
+`))
+	require.NoError(t, err)
+	api := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// binary for the docs UI, not for the API
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("api"))
+	})
+	restEvent := func(method string, path string) string {
+		return mustMarshal(t, events.APIGatewayProxyRequest{
+			HTTPMethod: method,
+			Path:       path,
+			Headers:    map[string]string{"Host": "abc123.execute-api.eu-west-1.amazonaws.com"},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				AccountID: "123456789012",
+				Stage:     "prod",
+			},
+		})
+	}
+	httpAPIEvent := mustMarshal(t, events.APIGatewayV2HTTPRequest{
+		Version:  "2.0",
+		RouteKey: "$default",
+		RawPath:  "/beta/openapi.json",
+		Headers:  map[string]string{"host": "pets.example.com"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Stage: "beta",
+			HTTP:  events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	// valid UTF-8, so only its media type makes it binary
+	logo := []byte("PNG\r\n")
+
+	tests := []struct {
+		name         string
+		opts         []httpbridge.Option
+		event        string
+		wantStatus   int
+		wantType     string
+		wantBody     []string
+		wantBase64   bool
+		wantLocation string
+		wantHeader   map[string]string
+	}{
+		{
+			name:       "JSON with the stage of a REST API",
+			event:      restEvent(http.MethodGet, "/openapi.json"),
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody: []string{
+				`"servers":[{"description":"Production","url":"https://abc123.execute-api.eu-west-1.amazonaws.com/prod"}]`,
+			},
+		},
+		{
+			name:       "YAML",
+			event:      restEvent(http.MethodGet, "/openapi.yaml"),
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			wantBody:   []string{"openapi: 3.0.3", "url: https://abc123.execute-api.eu-west-1.amazonaws.com/prod"},
+		},
+		{
+			name:       "HTTP API stage",
+			opts:       []httpbridge.Option{httpbridge.BasePath("")},
+			event:      httpAPIEvent,
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   []string{`"url":"https://pets.example.com/beta"`},
+		},
+		{
+			name:       "other methods reach the handler",
+			event:      restEvent(http.MethodPost, "/openapi.json"),
+			wantStatus: http.StatusOK,
+			wantBody:   []string{"api"},
+		},
+		{
+			name:       "no docs UI unless asked",
+			event:      restEvent(http.MethodGet, "/docs/"),
+			wantStatus: http.StatusOK,
+			wantBody:   []string{"api"},
+		},
+		{
+			name:       "built-in docs UI",
+			opts:       []httpbridge.Option{httpbridge.ServeDocsUI(nil)},
+			event:      restEvent(http.MethodGet, "/docs/"),
+			wantStatus: http.StatusOK,
+			wantType:   "text/html; charset=utf-8",
+			wantBody:   []string{"<title>API reference</title>", `fetch("../openapi.json")`},
+		},
+		{
+			name:         "docs UI without trailing slash",
+			opts:         []httpbridge.Option{httpbridge.ServeDocsUI(nil)},
+			event:        restEvent(http.MethodGet, "/docs"),
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "docs/",
+		},
+		{
+			name:       "docs media types are only binary in the docs UI",
+			opts:       []httpbridge.Option{httpbridge.ServeDocsUI(nil)},
+			event:      restEvent(http.MethodGet, "/pets"),
+			wantStatus: http.StatusOK,
+			wantType:   "image/png",
+			wantBody:   []string{"api"},
+		},
+		{
+			name: "HTTPMiddleware wraps the spec routes",
+			opts: []httpbridge.Option{httpbridge.HTTPMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+					next.ServeHTTP(w, r)
+				})
+			})},
+			event:      restEvent(http.MethodGet, "/openapi.json"),
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			name: "custom docs UI",
+			opts: []httpbridge.Option{httpbridge.ServeDocsUI(fstest.MapFS{
+				"index.html": {Data: []byte("<h1>docs</h1>")},
+				"logo.png":   {Data: logo},
+			})},
+			event:      restEvent(http.MethodGet, "/docs/logo.png"),
+			wantStatus: http.StatusOK,
+			wantType:   "image/png",
+			wantBody:   []string{string(logo)},
+			wantBase64: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]httpbridge.Option{httpbridge.ServeOpenAPI(spec)}, tt.opts...)
+			resp, err := httpbridge.ServeHTTP(api, opts...).Invoke(context.Background(), []byte(tt.event))
+			require.NoError(t, err)
+
+			var out events.APIGatewayProxyResponse
+			require.NoError(t, json.Unmarshal(resp, &out))
+			require.Equal(t, tt.wantStatus, out.StatusCode, out.Body)
+			assert.Equal(t, tt.wantBase64, out.IsBase64Encoded)
+			if tt.wantType != "" {
+				assert.Equal(t, tt.wantType, out.Headers["Content-Type"])
+			}
+			if tt.wantLocation != "" {
+				assert.Equal(t, tt.wantLocation, out.Headers["Location"])
+			}
+			for name, want := range tt.wantHeader {
+				assert.Equal(t, want, out.Headers[name])
+			}
+			body := out.Body
+			if out.IsBase64Encoded {
+				decoded, err := base64.StdEncoding.DecodeString(out.Body)
+				require.NoError(t, err)
+				body = string(decoded)
+			}
+			for _, want := range tt.wantBody {
+				assert.Contains(t, body, want)
+			}
+		})
+	}
+}
+
+func Test_ServeHTTP_OpenAPIErrorRendering(t *testing.T) {
+	spec := &openapi3.T{OpenAPI: "3.0.3", Extensions: map[string]any{"x-unencodable": func() {}}}
+	renderer := func(_ context.Context, w http.ResponseWriter, _ int, _ error) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+	event := mustMarshal(t, events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/openapi.json",
+		RequestContext: events.APIGatewayProxyRequestContext{AccountID: "123456789012"},
+	})
+
+	resp, err := httpbridge.ServeHTTP(http.NotFoundHandler(),
+		httpbridge.ServeOpenAPI(spec),
+		httpbridge.ErrorRendering(renderer),
+	).Invoke(context.Background(), []byte(event))
+	require.NoError(t, err)
+
+	var out events.APIGatewayProxyResponse
+	require.NoError(t, json.Unmarshal(resp, &out))
+	assert.Equal(t, http.StatusTeapot, out.StatusCode)
+}
+
+func Test_ServeHTTP_DocsUIWithoutOpenAPI(t *testing.T) {
+	assert.Panics(t, func() {
+		httpbridge.ServeHTTP(http.NotFoundHandler(), httpbridge.ServeDocsUI(nil))
+	})
+}
+
+type eventSource struct {
+	name     string
+	withPath func(t *testing.T, path string, rawQuery string) string
+}
+
+var eventSources = []eventSource{
+	{
+		name: "API Gateway - REST",
+		withPath: func(t *testing.T, path string, rawQuery string) string {
+			t.Helper()
+			query, err := url.ParseQuery(rawQuery)
+			require.NoError(t, err)
+			return mustMarshal(t, events.APIGatewayProxyRequest{
+				HTTPMethod:                      http.MethodGet,
+				Path:                            path,
+				MultiValueQueryStringParameters: query,
+				RequestContext:                  events.APIGatewayProxyRequestContext{AccountID: "123456789012"},
+			})
+		},
+	},
+	{
+		name: "API Gateway - HTTP",
+		withPath: func(t *testing.T, path string, rawQuery string) string {
+			t.Helper()
+			return mustMarshal(t, events.APIGatewayV2HTTPRequest{
+				Version:        "2.0",
+				RawPath:        path,
+				RawQueryString: rawQuery,
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+				},
+			})
+		},
+	},
+	{
+		name: "ALB Target Group",
+		withPath: func(t *testing.T, path string, rawQuery string) string {
+			t.Helper()
+			query, err := url.ParseQuery(rawQuery)
+			require.NoError(t, err)
+			params := make(map[string]string, len(query))
+			for k := range query {
+				params[k] = query.Get(k)
+			}
+			return mustMarshal(t, events.ALBTargetGroupRequest{
+				HTTPMethod:            http.MethodGet,
+				Path:                  path,
+				QueryStringParameters: params,
+				RequestContext: events.ALBTargetGroupRequestContext{
+					ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:region:123456789012:targetgroup/tg/1"},
+				},
+			})
+		},
+	},
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func statusCodeOf(t *testing.T, resp []byte) int {
+	t.Helper()
+	var out struct {
+		StatusCode int `json:"statusCode"`
+	}
+	require.NoError(t, json.Unmarshal(resp, &out))
+	return out.StatusCode
+}
+
+func captureRequest(t *testing.T, reqJSON string, opts ...httpbridge.Option) *http.Request {
+	t.Helper()
+	var captured *http.Request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r
+		w.WriteHeader(http.StatusNoContent)
+	})
+	_, err := httpbridge.ServeHTTP(handler, opts...).Invoke(context.Background(), []byte(reqJSON))
+	require.NoError(t, err)
+	require.NotNil(t, captured)
+	return captured
+}
+
 var (
 	//go:embed testpayloads/alb_target_group.json
 	albTargetGroupHelloWorldRequest string
 	//go:embed testpayloads/apigateway_rest.json
 	apiGatewayHelloWorldRequest string
+	//go:embed testpayloads/apigateway_http.json
+	apiGatewayHTTPHelloWorldRequest string
+	//go:embed testpayloads/query/apigateway_rest.json
+	apiGatewayQueryRequest string
+	//go:embed testpayloads/query/apigateway_http.json
+	apiGatewayHTTPQueryRequest string
+	//go:embed testpayloads/query/alb_multi_value.json
+	albMultiValueQueryRequest string
+	//go:embed testpayloads/query/alb_single_value.json
+	albSingleValueQueryRequest string
 )
@@ -0,0 +1,55 @@
+// Package httpbridgetest stands in for the services the bridge talks to in
+// tests.
+package httpbridgetest
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/geode-io/golambdas/httpbridge"
+)
+
+// Object is what was stored under a key.
+type Object struct {
+	ContentType     string
+	ContentEncoding string
+	Body            []byte
+}
+
+// MemoryStore is an httpbridge.ObjectStore keeping objects in memory.
+type MemoryStore struct {
+	baseURL string
+
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+var _ httpbridge.ObjectStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a store whose objects are at baseURL followed by
+// their key.
+func NewMemoryStore(baseURL string) *MemoryStore {
+	return &MemoryStore{baseURL: baseURL, objects: map[string]Object{}}
+}
+
+func (s *MemoryStore) Put(
+	_ context.Context,
+	key string,
+	contentType string,
+	contentEncoding string,
+	body []byte,
+) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = Object{ContentType: contentType, ContentEncoding: contentEncoding, Body: body}
+	return url.JoinPath(s.baseURL, key)
+}
+
+// Get returns the object stored under key.
+func (s *MemoryStore) Get(key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.objects[key]
+	return object, ok
+}
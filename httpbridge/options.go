@@ -1,34 +1,205 @@
 package httpbridge
 
 import (
+	"context"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/oapi-codegen/runtime/strictmiddleware/nethttp"
+
+	"github.com/geode-io/golambdas/lambdalog"
+	"github.com/geode-io/golambdas/lambdaotel"
 )
 
-type apiOptions struct {
+type options struct {
 	lambdaMiddlewares   []func(lambda.Handler) lambda.Handler
+	lambdaOptions       []lambda.Option
 	strictMiddlewares   []nethttp.StrictHTTPMiddlewareFunc
+	requestErrors       ErrorHandlerFunc
+	responseErrors      ErrorHandlerFunc
 	lowLevelMiddlewares []func(http.Handler) http.Handler
+	errorRenderer       ErrorRenderer
+	binaryContentTypes  []string
+	logger              *slog.Logger
+	trustedProxies      *TrustedProxies
+	basePath            *string
+	onPanic             func(ctx context.Context, recovered any, stack []byte)
+	deadlineMargin      time.Duration
+	payloads            lambdalog.Payloads
+	tracing             []lambdaotel.Option
+	maxResponseSize     int
+	oversizePolicy      OversizePolicy
+	spec                *openapi3.T
+	docs                fs.FS
 }
 
-type APIOption func(*apiOptions)
+// Option configures ServeAPI, ServeHTTP and the event specific Serve*
+// functions alike.
+type Option func(*options)
+
+// APIOption is the name Option had when only ServeAPI took options.
+//
+// Deprecated: use Option.
+type APIOption = Option
+
+func newOptions(opts []Option) options {
+	useOpts := options{
+		errorRenderer:      RenderProblem,
+		requestErrors:      WriteRequestErrorProblem,
+		responseErrors:     WriteResponseErrorProblem,
+		binaryContentTypes: []string{mimeTypeApplicationOctetStream},
+	}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+	return useOpts
+}
 
-func APIMiddleware(middlewares ...nethttp.StrictHTTPMiddlewareFunc) APIOption {
-	return func(o *apiOptions) {
+// APIMiddleware adds strict server middlewares; only ServeAPI uses them.
+func APIMiddleware(middlewares ...nethttp.StrictHTTPMiddlewareFunc) Option {
+	return func(o *options) {
 		o.strictMiddlewares = append(o.strictMiddlewares, middlewares...)
 	}
 }
 
-func HTTPMiddleware(middlewares ...func(http.Handler) http.Handler) APIOption {
-	return func(o *apiOptions) {
+// APIRequestErrorHandler replaces WriteRequestErrorProblem as the handler of
+// the errors strict servers fail to decode requests with; only
+// ServeAPIWithOptions uses it.
+func APIRequestErrorHandler(handle ErrorHandlerFunc) Option {
+	return func(o *options) {
+		o.requestErrors = handle
+	}
+}
+
+// APIResponseErrorHandler replaces WriteResponseErrorProblem as the handler of
+// the errors strict handlers return; only ServeAPIWithOptions uses it.
+func APIResponseErrorHandler(handle ErrorHandlerFunc) Option {
+	return func(o *options) {
+		o.responseErrors = handle
+	}
+}
+
+func HTTPMiddleware(middlewares ...func(http.Handler) http.Handler) Option {
+	return func(o *options) {
 		o.lowLevelMiddlewares = append(o.lowLevelMiddlewares, middlewares...)
 	}
 }
 
-func LambdaMiddleware(middlewares ...func(lambda.Handler) lambda.Handler) APIOption {
-	return func(o *apiOptions) {
+// LambdaMiddleware wraps the lambda.Handler the bridge returns, so it sees
+// every invocation as raw JSON before it is canonized and after the response
+// is transcoded.
+func LambdaMiddleware(middlewares ...func(lambda.Handler) lambda.Handler) Option {
+	return func(o *options) {
 		o.lambdaMiddlewares = append(o.lambdaMiddlewares, middlewares...)
 	}
 }
+
+// LambdaOptions are passed on to lambda.NewHandlerWithOptions after the
+// bridge's own.
+func LambdaOptions(lambdaOptions ...lambda.Option) Option {
+	return func(o *options) {
+		o.lambdaOptions = append(o.lambdaOptions, lambdaOptions...)
+	}
+}
+
+// ErrorRendering replaces RenderProblem as the renderer for requests the
+// bridge fails to canonize or transcode, and for the OpenAPI spec when it
+// fails to encode it.
+func ErrorRendering(renderer ErrorRenderer) Option {
+	return func(o *options) {
+		o.errorRenderer = renderer
+	}
+}
+
+// BinaryContentTypes adds media types whose response bodies are always
+// base64-encoded, such as "image/png" or "image/*". Bodies that aren't valid
+// UTF-8, or have a Content-Encoding, are encoded regardless.
+func BinaryContentTypes(patterns ...string) Option {
+	return func(o *options) {
+		o.binaryContentTypes = append(o.binaryContentTypes, patterns...)
+	}
+}
+
+// Logger sets the logger the bridge logs with, and hands to the handler
+// through lambdalog.FromContext, instead of lambdalog.Default(), which logs
+// the way the function's logging configuration asks. Either way records carry
+// the invocation's metadata.
+func Logger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// TrustProxies resolves r.RemoteAddr from X-Forwarded-For according to
+// policy, before any HTTPMiddleware sees the request.
+func TrustProxies(policy TrustedProxies) Option {
+	return func(o *options) {
+		o.trustedProxies = &policy
+	}
+}
+
+// BasePath strips the stage and basePath from request paths, before any
+// HTTPMiddleware sees the request. See StripBasePath.
+func BasePath(basePath string) Option {
+	return func(o *options) {
+		o.basePath = &basePath
+	}
+}
+
+// OnPanic is told about every panic the bridge recovers from in the handler,
+// e.g. to count it or send it to an error tracker. The client gets a 500 from
+// the ErrorRenderer either way.
+func OnPanic(hook func(ctx context.Context, recovered any, stack []byte)) Option {
+	return func(o *options) {
+		o.onPanic = hook
+	}
+}
+
+// DeadlineMargin gives the handler a context that expires margin before the
+// invocation does. A handler still running by then is abandoned and the client
+// gets a 504 from the ErrorRenderer, while there is still time to send one.
+func DeadlineMargin(margin time.Duration) Option {
+	return func(o *options) {
+		o.deadlineMargin = margin
+	}
+}
+
+// PayloadLogging controls how the bridge logs the events it receives and the
+// responses it returns, and what httpmiddleware.Logging logs of them.
+func PayloadLogging(payloads lambdalog.Payloads) Option {
+	return func(o *options) {
+		o.payloads = payloads
+	}
+}
+
+// Tracing starts an OpenTelemetry span for every invocation and, inside it,
+// one for the HTTP request, see lambdaotel.Invocation and lambdaotel.HTTP.
+// Invocation spans have the http faas.trigger.
+// They wrap every LambdaMiddleware and HTTPMiddleware respectively; the
+// request span starts once the bridge has stripped the base path and resolved
+// the client address, so it records both as the handler sees them.
+func Tracing(opts ...lambdaotel.Option) Option {
+	return func(o *options) {
+		o.tracing = append([]lambdaotel.Option{}, opts...)
+	}
+}
+
+// OversizedResponses replaces FailOversized(500) as the policy for responses
+// too large for Lambda to return, accounting for base64 and JSON encoding.
+func OversizedResponses(policy OversizePolicy) Option {
+	return func(o *options) {
+		o.oversizePolicy = policy
+	}
+}
+
+// ResponseSizeLimit replaces MaxResponseSize, or MaxALBResponseSize for ALB
+// events, as the size past which responses are oversized.
+func ResponseSizeLimit(size int) Option {
+	return func(o *options) {
+		o.maxResponseSize = size
+	}
+}
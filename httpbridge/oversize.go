@@ -0,0 +1,166 @@
+package httpbridge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// MaxResponseSize is the most a synchronously invoked function can return, in
+// bytes. Lambda fails invocations whose responses are any larger.
+const MaxResponseSize = 6291556
+
+// MaxALBResponseSize is the most a function can return to an ALB, in bytes.
+// The ALB answers clients with a 502 when a response is any larger.
+const MaxALBResponseSize = 1 << 20
+
+// ErrResponseTooLarge is the error oversized responses are rendered with,
+// unless they are truncated or offloaded.
+var ErrResponseTooLarge = errors.New("response too large for Lambda to return")
+
+// ObjectStore keeps response bodies too large for Lambda to return, such as
+// an S3 bucket clients are given presigned URLs for.
+type ObjectStore interface {
+	// Put stores body under key and returns the URL clients can get it from,
+	// which must answer with contentType and, unless it is empty,
+	// contentEncoding, such as the gzip Compress encoded body with.
+	Put(ctx context.Context, key string, contentType string, contentEncoding string, body []byte) (string, error)
+}
+
+// OversizePolicy is what the bridge does with responses too large to return
+// once encoded as an event response, see OversizedResponses.
+type OversizePolicy struct {
+	statusCode int
+	truncate   bool
+	store      ObjectStore
+}
+
+// FailOversized replaces oversized responses with the rendering of
+// ErrResponseTooLarge, with statusCode, which must be 413 or 500. It is the
+// default, with 500.
+func FailOversized(statusCode int) OversizePolicy {
+	if statusCode != http.StatusRequestEntityTooLarge && statusCode != http.StatusInternalServerError {
+		panic(fmt.Sprintf("httpbridge: FailOversized with status %d instead of 413 or 500", statusCode))
+	}
+	return OversizePolicy{statusCode: statusCode}
+}
+
+// TruncateOversized cuts oversized bodies down to what fits. Clients are not
+// told, so it suits bodies that are useful in part, such as logs; truncated
+// JSON or compressed bodies are not.
+func TruncateOversized() OversizePolicy {
+	return OversizePolicy{truncate: true}
+}
+
+// OffloadOversized puts oversized bodies in store, under the Lambda request
+// ID and with their Content-Type and Content-Encoding, and answers with a 303
+// redirecting the client to them. Only the response's cookies are kept.
+func OffloadOversized(store ObjectStore) OversizePolicy {
+	return OversizePolicy{store: store}
+}
+
+// limitResponse applies b's OversizePolicy to w if it is too large to return
+// for req.
+func (b *bridge) limitResponse(ctx context.Context, req lambdaHTTPRequest, w *lambdaHTTPResponseWriter) error {
+	limit := b.maxResponseSize
+	if limit == 0 {
+		limit = maxResponseSize(req)
+	}
+	size := w.encodedSize()
+	if size <= limit {
+		return nil
+	}
+	b.logger.WarnContext(ctx, "response too large for Lambda to return",
+		"response.status", w.statusCode, "response.encoded_size", size, "limit", limit)
+
+	switch {
+	case b.oversizePolicy.truncate:
+		if w.truncate(limit) {
+			return nil
+		}
+	case b.oversizePolicy.store != nil:
+		return b.offload(ctx, w)
+	}
+	statusCode := b.oversizePolicy.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	return &requestError{statusCode: statusCode, detail: "response too large", err: ErrResponseTooLarge}
+}
+
+// maxResponseSize is the most the event source of req takes from Lambda.
+func maxResponseSize(req lambdaHTTPRequest) int {
+	if _, ok := req.(*albRequest); ok {
+		return MaxALBResponseSize
+	}
+	return MaxResponseSize
+}
+
+func (b *bridge) offload(ctx context.Context, w *lambdaHTTPResponseWriter) error {
+	key, err := objectKey(ctx)
+	if err != nil {
+		return err
+	}
+	body := append([]byte(nil), w.body.Bytes()...)
+	location, err := b.oversizePolicy.store.Put(ctx, key,
+		w.header.Get(contentTypeHeader), w.header.Get(contentEncodingHeader), body)
+	if err != nil {
+		return fmt.Errorf("failed to offload oversized response: %w", err)
+	}
+
+	cookies := w.header.Values(setCookieHeader)
+	w.reset()
+	for _, cookie := range cookies {
+		w.Header().Add(setCookieHeader, cookie)
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusSeeOther)
+	return nil
+}
+
+func objectKey(ctx context.Context) (string, error) {
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.AwsRequestID != "" {
+		return lc.AwsRequestID, nil
+	}
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate object key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// truncate cuts the body down so the event response fits in limit, and
+// reports whether it could.
+func (l *lambdaHTTPResponseWriter) truncate(limit int) bool {
+	budget := limit - (l.encodedSize() - l.encodedBodySize()) - 2
+	if budget < 0 {
+		return false
+	}
+	body := l.body.Bytes()
+	keep := 0
+	if l.isBinary() {
+		keep = base64.StdEncoding.DecodedLen(budget)
+	} else {
+		for i, r := range l.body.String() {
+			if budget -= jsonRuneSize(r); budget < 0 {
+				break
+			}
+			keep = i + utf8.RuneLen(r)
+		}
+	}
+	keep = max(min(keep, len(body)), 0)
+	l.body.Truncate(keep)
+	if l.header.Get("Content-Length") != "" {
+		l.header.Set("Content-Length", strconv.Itoa(keep))
+	}
+	// cutting can turn a binary body into text, which may encode larger
+	return l.encodedSize() <= limit
+}
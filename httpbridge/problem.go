@@ -0,0 +1,116 @@
+package httpbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+const mimeTypeApplicationProblemJSON = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// RequestID is the Lambda request ID of the invocation, so clients can
+	// quote something the function's logs can be searched for.
+	RequestID string `json:"requestId,omitempty"`
+	// Errors lists what exactly was wrong with the request, such as each of
+	// its invalid parameters and body fields.
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError is one of the reasons a request was rejected.
+type ProblemError struct {
+	Detail string `json:"detail"`
+	// Pointer is a JSON pointer to the offending part of the request body.
+	Pointer string `json:"pointer,omitempty"`
+	// Parameter is the name of the offending parameter, and In where it was:
+	// "path", "query", "header" or "cookie".
+	Parameter string `json:"parameter,omitempty"`
+	In        string `json:"in,omitempty"`
+}
+
+// NewProblem returns the problem for statusCode, stamped with the Lambda
+// request ID found in ctx.
+func NewProblem(ctx context.Context, statusCode int, detail string) Problem {
+	problem := Problem{
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		problem.RequestID = lc.AwsRequestID
+	}
+	return problem
+}
+
+// WriteProblem writes problem to w as application/problem+json.
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(w, http.StatusText(problem.Status), problem.Status)
+		return
+	}
+	w.Header().Set(contentTypeHeader, mimeTypeApplicationProblemJSON)
+	w.WriteHeader(problem.Status)
+	_, _ = w.Write(body)
+}
+
+// ErrorRenderer writes the response for an invocation the bridge could not
+// hand to the handler, or could not turn the handler's response into an
+// event. statusCode is 4xx when the event itself was malformed, 504 when the
+// handler ran past its DeadlineMargin, that of the OversizePolicy when the
+// response was too large, and 500 otherwise.
+type ErrorRenderer func(ctx context.Context, w http.ResponseWriter, statusCode int, err error)
+
+// RenderProblem is the default ErrorRenderer. It never exposes err itself;
+// client errors get a short description of what was wrong with the request.
+func RenderProblem(ctx context.Context, w http.ResponseWriter, statusCode int, err error) {
+	WriteProblem(w, NewProblem(ctx, statusCode, publicDetail(err)))
+}
+
+// requestError is a failure the client is told about: a canonization failure
+// caused by what it sent, or a response too large to return.
+type requestError struct {
+	statusCode int
+	detail     string
+	err        error
+}
+
+func (e *requestError) Error() string {
+	return e.detail + ": " + e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func malformedRequest(detail string, err error) error {
+	return &requestError{statusCode: http.StatusBadRequest, detail: detail, err: err}
+}
+
+func errorStatus(err error) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.statusCode
+	case errors.Is(err, ErrHandlerTimeout):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func publicDetail(err error) string {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.detail
+	}
+	return ""
+}
@@ -1,14 +1,17 @@
 package httpbridge
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -40,148 +43,213 @@ var _ lambdaHTTPRequest = (*apiGatewayV1Request)(nil)
 var _ lambdaHTTPRequest = (*albRequest)(nil)
 
 var (
-	hostHeader = http.CanonicalHeaderKey("host")
+	hostHeader           = http.CanonicalHeaderKey("host")
+	cookieHeader         = http.CanonicalHeaderKey("cookie")
+	forwardedForHeader   = http.CanonicalHeaderKey("x-forwarded-for")
+	forwardedProtoHeader = http.CanonicalHeaderKey("x-forwarded-proto")
+	forwardedPortHeader  = http.CanonicalHeaderKey("x-forwarded-port")
 )
 
-func (r *apiGatewayV2Request) Canonize(ctx context.Context) (*http.Request, error) {
-	rawQuery := r.RawQueryString
-	if len(rawQuery) == 0 {
-		params := url.Values{}
-		for k, v := range r.QueryStringParameters {
-			params.Add(k, v)
-		}
-		rawQuery = params.Encode()
-	}
-
-	headers := make(http.Header)
-	for k, v := range r.Headers {
-		headers.Add(k, v)
-	}
+const (
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+)
 
-	path, err := url.PathUnescape(r.RawPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unescape path %s from request: %w", r.RawPath, err)
-	}
+// canonicalRequest is the event-agnostic shape every lambdaHTTPRequest is
+// reduced to before it is turned into an *http.Request.
+type canonicalRequest struct {
+	event           lambdaHTTPRequest
+	method          string
+	path            string
+	rawQuery        string
+	headers         http.Header
+	domainName      string
+	sourceIP        string
+	body            string
+	isBase64Encoded bool
+}
 
+func (c *canonicalRequest) build(ctx context.Context) (*http.Request, error) {
+	scheme := forwardedScheme(c.headers)
+	host := forwardedHost(c.headers, c.domainName, scheme)
 	u := url.URL{
-		Host:     headers.Get(hostHeader),
-		Path:     path,
-		RawQuery: rawQuery,
+		Scheme:   scheme,
+		Host:     host,
+		RawQuery: c.rawQuery,
+	}
+	if err := setEscapedPath(&u, c.path); err != nil {
+		return nil, err
 	}
 
-	var body io.Reader
-	if r.IsBase64Encoded {
-		body = base64.NewDecoder(base64.StdEncoding, body)
-	} else {
-		body = strings.NewReader(r.Body)
+	// decoding up front both sets ContentLength and lets a bad encoding be
+	// answered with a 400 rather than surface as a read error in the handler
+	var body io.Reader = strings.NewReader(c.body)
+	if c.isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(c.body)
+		if err != nil {
+			return nil, malformedRequest("malformed request body", fmt.Errorf("failed to decode base64 body: %w", err))
+		}
+		body = bytes.NewReader(decoded)
 	}
 
-	out, err := http.NewRequestWithContext(ctx, r.RequestContext.HTTP.Method, u.String(), body)
+	out, err := http.NewRequestWithContext(withEvent(ctx, c.event), c.method, u.String(), body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to canonize incoming http request: %w", err)
 	}
-	out.RemoteAddr = r.RequestContext.HTTP.SourceIP
+	out.Host = host
+	out.RemoteAddr = remoteAddr(c.sourceIP)
 	out.RequestURI = u.RequestURI()
-	out.Header = headers
+	out.Header = c.headers
 	return out, nil
 }
 
-func (r *apiGatewayV1Request) Canonize(ctx context.Context) (*http.Request, error) {
-	params := url.Values{}
-	for k, v := range r.QueryStringParameters {
-		params.Add(k, v)
-	}
-	for k, v := range r.MultiValueQueryStringParameters {
-		for _, vv := range v {
-			params.Add(k, vv)
-		}
-	}
-	rawQuery := params.Encode()
-
-	headers := make(http.Header)
-	for k, v := range r.Headers {
-		headers.Add(k, v)
-	}
-	for k, v := range r.MultiValueHeaders {
-		headers[k] = v
+// setEscapedPath keeps the path exactly as the client escaped it, so that an
+// encoded slash in an ID doesn't turn into a path separator.
+func setEscapedPath(u *url.URL, escaped string) error {
+	if escaped == "" {
+		escaped = "/"
 	}
-
-	path, err := url.PathUnescape(r.Path)
+	path, err := url.PathUnescape(escaped)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unescape path %s from request: %w", r.Path, err)
+		err = fmt.Errorf("failed to unescape path %s from request: %w", escaped, err)
+		return malformedRequest("malformed request path", err)
 	}
-
-	u := url.URL{
-		Host:     headers.Get(hostHeader),
-		Path:     path,
-		RawQuery: rawQuery,
+	u.Path = path
+	if u.EscapedPath() != escaped {
+		u.RawPath = escaped
 	}
+	return nil
+}
 
-	var body io.Reader
-	if r.IsBase64Encoded {
-		body = base64.NewDecoder(base64.StdEncoding, body)
+// encodeQuery rebuilds a query string from an event's decoded parameters.
+// The multi-value map is a superset of the single-value one when both are
+// present. Neither map remembers the order keys arrived in, so keys are
+// sorted, but repeated values keep their order.
+func encodeQuery(single map[string]string, multi map[string][]string) string {
+	params := make(url.Values, len(single)+len(multi))
+	if len(multi) > 0 {
+		for k, v := range multi {
+			params[k] = v
+		}
 	} else {
-		body = strings.NewReader(r.Body)
+		for k, v := range single {
+			params.Set(k, v)
+		}
 	}
+	return params.Encode()
+}
 
-	out, err := http.NewRequestWithContext(ctx, r.HTTPMethod, u.String(), body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to canonize incoming http request: %w", err)
+// joinEncodedQuery is encodeQuery for ALB events, which deliver keys and
+// values still URL-encoded exactly as the client sent them.
+func joinEncodedQuery(single map[string]string, multi map[string][]string) string {
+	if len(multi) == 0 {
+		multi = make(map[string][]string, len(single))
+		for k, v := range single {
+			multi[k] = []string{v}
+		}
 	}
-	out.RemoteAddr = r.RequestContext.Identity.SourceIP
-	out.RequestURI = u.RequestURI()
-	out.Header = headers
-	return out, nil
+
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(multi)) {
+		for _, v := range multi[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(k)
+			b.WriteByte('=')
+			b.WriteString(v)
+		}
+	}
+	return b.String()
 }
 
-func (r *albRequest) sourceIP() string {
-	if xff, ok := r.MultiValueHeaders["x-forwarded-for"]; ok && len(xff) > 0 {
-		ips := strings.SplitN(xff[0], ",", 2)
-		if len(ips) > 0 {
-			return ips[0]
+// canonicalHeaders merges the single and multi-value header maps of an event,
+// preferring the multi-value entries since they are a superset when both are
+// present.
+func canonicalHeaders(single map[string]string, multi map[string][]string) http.Header {
+	headers := make(http.Header, len(single)+len(multi))
+	for k, v := range multi {
+		key := http.CanonicalHeaderKey(k)
+		headers[key] = append(headers[key], v...)
+	}
+	for k, v := range single {
+		key := http.CanonicalHeaderKey(k)
+		if _, ok := headers[key]; ok {
+			continue
 		}
+		headers[key] = []string{v}
 	}
-	return ""
+	return headers
 }
 
-func (r *albRequest) Canonize(ctx context.Context) (*http.Request, error) {
-	params := url.Values{}
-	for k, v := range r.QueryStringParameters {
-		params.Add(k, v)
+func (r *apiGatewayV2Request) Canonize(ctx context.Context) (*http.Request, error) {
+	// HTTP APIs comma-join repeated parameters, so the raw query string is
+	// the only faithful source; the map is a fallback for hand-made events
+	rawQuery := r.RawQueryString
+	if len(rawQuery) == 0 {
+		rawQuery = encodeQuery(r.QueryStringParameters, nil)
 	}
-	rawQuery := params.Encode()
 
-	headers := make(http.Header)
-	for k, v := range r.Headers {
-		headers.Add(k, v)
+	// HTTP APIs lift the Cookie header out into its own array
+	headers := canonicalHeaders(r.Headers, nil)
+	if len(r.Cookies) > 0 {
+		headers[cookieHeader] = append(headers[cookieHeader], strings.Join(r.Cookies, "; "))
 	}
 
-	path, err := url.PathUnescape(r.Path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unescape path %s from request: %w", r.Path, err)
+	req := canonicalRequest{
+		event:           r,
+		method:          r.RequestContext.HTTP.Method,
+		path:            r.RawPath,
+		rawQuery:        rawQuery,
+		headers:         headers,
+		domainName:      r.RequestContext.DomainName,
+		sourceIP:        r.RequestContext.HTTP.SourceIP,
+		body:            r.Body,
+		isBase64Encoded: r.IsBase64Encoded,
 	}
+	return req.build(ctx)
+}
 
-	u := url.URL{
-		Host:     headers.Get(hostHeader),
-		Path:     path,
-		RawQuery: rawQuery,
+func (r *apiGatewayV1Request) Canonize(ctx context.Context) (*http.Request, error) {
+	req := canonicalRequest{
+		event:           r,
+		method:          r.HTTPMethod,
+		path:            r.Path,
+		rawQuery:        encodeQuery(r.QueryStringParameters, r.MultiValueQueryStringParameters),
+		headers:         canonicalHeaders(r.Headers, r.MultiValueHeaders),
+		domainName:      r.RequestContext.DomainName,
+		sourceIP:        r.RequestContext.Identity.SourceIP,
+		body:            r.Body,
+		isBase64Encoded: r.IsBase64Encoded,
 	}
+	return req.build(ctx)
+}
 
-	var body io.Reader
-	if r.IsBase64Encoded {
-		body = base64.NewDecoder(base64.StdEncoding, body)
-	} else {
-		body = strings.NewReader(r.Body)
+// newResponse prepares a response in the same header mode the target group
+// used for the request.
+func (r *albRequest) newResponse() *albResponse {
+	resp := &albResponse{}
+	if len(r.MultiValueHeaders) > 0 {
+		resp.MultiValueHeaders = make(map[string][]string)
 	}
+	return resp
+}
 
-	out, err := http.NewRequestWithContext(ctx, r.HTTPMethod, u.String(), body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to canonize incoming http request: %w", err)
+func (r *albRequest) Canonize(ctx context.Context) (*http.Request, error) {
+	headers := canonicalHeaders(r.Headers, r.MultiValueHeaders)
+	req := canonicalRequest{
+		event:    r,
+		method:   r.HTTPMethod,
+		path:     r.Path,
+		rawQuery: joinEncodedQuery(r.QueryStringParameters, r.MultiValueQueryStringParameters),
+		headers:  headers,
+		// the ALB appends the address of whoever connected to it as the last
+		// X-Forwarded-For entry, so that is the only one we can vouch for
+		sourceIP:        TrustedProxies{}.Resolve("", forwardedFor(headers)),
+		body:            r.Body,
+		isBase64Encoded: r.IsBase64Encoded,
 	}
-	out.RemoteAddr = r.sourceIP()
-	out.RequestURI = u.RequestURI()
-	out.Header = headers
-	return out, nil
+	return req.build(ctx)
 }
 
 var (
@@ -199,7 +267,7 @@ func demuxAmbiguousRequest(payload json.RawMessage, rw *lambdaHTTPResponseWriter
 	case ambiguous.RequestContext.ELB.TargetGroupArn != "":
 		var albReq albRequest
 		err := json.Unmarshal(payload, &albReq)
-		rw.preparedResponse = &albResponse{}
+		rw.preparedResponse = albReq.newResponse()
 		return &albReq, err
 	// V2 may also have an account ID
 	case ambiguous.Version == "2.0":
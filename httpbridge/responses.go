@@ -5,8 +5,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -15,6 +17,7 @@ var (
 	setCookieHeader        = http.CanonicalHeaderKey("set-cookie")
 	contentTypeHeader      = http.CanonicalHeaderKey("content-type")
 	transferEncodingHeader = http.CanonicalHeaderKey("transfer-encoding")
+	contentEncodingHeader  = http.CanonicalHeaderKey("content-encoding")
 )
 
 const (
@@ -26,7 +29,16 @@ type lambdaHTTPResponseWriter struct {
 	body       bytes.Buffer
 	statusCode int
 
-	preparedResponse lambdaHTTPResponse
+	binaryContentTypes []string
+	preparedResponse   lambdaHTTPResponse
+}
+
+// reset discards everything written so far, so an error response can be
+// written in its place.
+func (l *lambdaHTTPResponseWriter) reset() {
+	l.header = nil
+	l.body.Reset()
+	l.statusCode = 0
 }
 
 func (w *lambdaHTTPResponseWriter) String() string {
@@ -83,9 +95,98 @@ func (l *lambdaHTTPResponseWriter) Flush() {
 	l.WriteHeader(http.StatusOK)
 }
 
+// encodedBody returns the body the way event responses carry it, base64
+// encoded when it is binary.
+func (l *lambdaHTTPResponseWriter) encodedBody() (string, bool) {
+	body := l.body.Bytes()
+	if l.isBinary() {
+		return base64.StdEncoding.EncodeToString(body), true
+	}
+	return string(body), false
+}
+
+func (l *lambdaHTTPResponseWriter) isBinary() bool {
+	// anything else would be mangled on its way through JSON
+	if !utf8.Valid(l.body.Bytes()) {
+		return true
+	}
+	// compressed bodies may happen to be valid UTF-8, but are binary all the same
+	if encoding := l.header.Get(contentEncodingHeader); encoding != "" && encoding != "identity" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(l.header.Get(contentTypeHeader))
+	if err != nil {
+		return false
+	}
+	for _, pattern := range l.binaryContentTypes {
+		if MatchMediaType(pattern, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// the part of an event response that doesn't depend on what was written: the
+// status code, isBase64Encoded and the names of the fields
+const responseEnvelopeSize = 128
+
+// encodedSize returns an upper bound on the size of the event response w is
+// transcoded into, once encoded as JSON.
+func (l *lambdaHTTPResponseWriter) encodedSize() int {
+	size := responseEnvelopeSize + l.encodedBodySize()
+	for name, values := range l.header {
+		for _, value := range values {
+			// repeated values may each be given their own copy of the name
+			size += jsonStringSize(name) + jsonStringSize(value) + 2
+		}
+	}
+	return size
+}
+
+func (l *lambdaHTTPResponseWriter) encodedBodySize() int {
+	if l.isBinary() {
+		return base64.StdEncoding.EncodedLen(l.body.Len()) + 2
+	}
+	return jsonStringSize(l.body.String())
+}
+
+// jsonStringSize returns the size of s encoded as a JSON string, escaping HTML
+// as encoding/json does by default.
+func jsonStringSize(s string) int {
+	size := 2
+	for _, r := range s {
+		size += jsonRuneSize(r)
+	}
+	return size
+}
+
+func jsonRuneSize(r rune) int {
+	switch {
+	case r == '"', r == '\\', r == '\n', r == '\r', r == '\t':
+		return 2
+	case r < 0x20, r == '<', r == '>', r == '&', r == '\u2028', r == '\u2029', r == utf8.RuneError:
+		return len(`\u0000`)
+	}
+	return utf8.RuneLen(r)
+}
+
+// MatchMediaType matches mediaType, lowercase as mime.ParseMediaType returns
+// it, against a pattern such as "image/png", "image/*" or "*/*".
+func MatchMediaType(pattern string, mediaType string) bool {
+	pattern = strings.ToLower(pattern)
+	if pattern == "*/*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return pattern == mediaType
+}
+
 func (r *apiGatewayV2Response) TranscodeFrom(httpResponse *lambdaHTTPResponseWriter) error {
 	r.StatusCode = httpResponse.statusCode
 	r.Headers = make(map[string]string)
+	r.Cookies = nil
 	for k, v := range httpResponse.header {
 		if k == setCookieHeader {
 			r.Cookies = append(r.Cookies, v...)
@@ -94,61 +195,83 @@ func (r *apiGatewayV2Response) TranscodeFrom(httpResponse *lambdaHTTPResponseWri
 
 		r.Headers[k] = strings.Join(v, ",")
 	}
-	// TODO: base64-encode other binary content-types as needed
-	contentType := httpResponse.header.Get(contentTypeHeader)
-	body := httpResponse.body.Bytes()
-	if contentType == mimeTypeApplicationOctetStream {
-		r.Body = base64.StdEncoding.EncodeToString(body)
-		r.IsBase64Encoded = true
-	} else {
-		r.Body = string(body)
-	}
+	r.Body, r.IsBase64Encoded = httpResponse.encodedBody()
 	return nil
 }
 
 func (r *apiGatewayV1Response) TranscodeFrom(httpResponse *lambdaHTTPResponseWriter) error {
 	r.StatusCode = httpResponse.statusCode
-	r.Headers = make(map[string]string)
-	for k, v := range httpResponse.header {
-		if len(v) > 1 {
+	// REST APIs merge both maps, so only repeated headers need the multi-value one
+	r.Headers, r.MultiValueHeaders = splitHeaders(httpResponse.header)
+	r.Body, r.IsBase64Encoded = httpResponse.encodedBody()
+	return nil
+}
+
+func (r *albResponse) TranscodeFrom(httpResponse *lambdaHTTPResponseWriter) error {
+	r.StatusCode = httpResponse.statusCode
+	r.StatusDescription = http.StatusText(httpResponse.statusCode)
+	// the target group only reads the header map matching its multi-value
+	// headers setting, which newResponse recorded from the request
+	if r.MultiValueHeaders != nil {
+		r.Headers = make(map[string]string)
+		r.MultiValueHeaders = make(map[string][]string, len(httpResponse.header))
+		for k, v := range httpResponse.header {
 			r.MultiValueHeaders[k] = v
-		} else if len(v) == 1 {
-			r.Headers[k] = v[0]
 		}
-	}
-	// TODO: base64-encode other binary content-types as needed
-	contentType := httpResponse.header.Get(contentTypeHeader)
-	body := httpResponse.body.Bytes()
-	if contentType == mimeTypeApplicationOctetStream {
-		r.Body = base64.StdEncoding.EncodeToString(body)
-		r.IsBase64Encoded = true
 	} else {
-		r.Body = string(body)
+		r.Headers = joinHeaders(httpResponse.header)
 	}
+	r.Body, r.IsBase64Encoded = httpResponse.encodedBody()
 	return nil
 }
 
-func (r *albResponse) TranscodeFrom(httpResponse *lambdaHTTPResponseWriter) error {
-	r.StatusCode = httpResponse.statusCode
-	r.StatusDescription = http.StatusText(httpResponse.statusCode)
-	r.Headers = make(map[string]string)
-	for k, v := range httpResponse.header {
+func splitHeaders(header http.Header) (map[string]string, map[string][]string) {
+	single := make(map[string]string, len(header))
+	multi := make(map[string][]string)
+	for k, v := range header {
 		if len(v) > 1 {
-			r.MultiValueHeaders[k] = v
+			multi[k] = v
 		} else if len(v) == 1 {
-			r.Headers[k] = v[0]
+			single[k] = v[0]
 		}
 	}
-	// TODO: base64-encode other binary content-types as needed
-	contentType := httpResponse.header.Get(contentTypeHeader)
-	body := httpResponse.body.Bytes()
-	if contentType == mimeTypeApplicationOctetStream {
-		r.Body = base64.StdEncoding.EncodeToString(body)
-		r.IsBase64Encoded = true
-	} else {
-		r.Body = string(body)
+	return single, multi
+}
+
+// joinHeaders flattens header for event sources that only take one value per
+// name. Repeated values are comma-joined, except for cookies which can't be, so
+// each one gets its own differently-cased spelling of Set-Cookie instead.
+func joinHeaders(header http.Header) map[string]string {
+	single := make(map[string]string, len(header))
+	for k, v := range header {
+		if len(v) == 0 {
+			continue
+		}
+		if k != setCookieHeader {
+			single[k] = strings.Join(v, ", ")
+			continue
+		}
+		for i, cookie := range v {
+			single[headerCaseVariant(setCookieHeader, i)] = cookie
+		}
 	}
-	return nil
+	return single
+}
+
+// headerCaseVariant returns the n-th distinct casing of name by flipping the
+// case of its letters along the bits of n, starting with name itself.
+func headerCaseVariant(name string, n int) string {
+	variant := []byte(name)
+	for i, c := range variant {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			continue
+		}
+		if n&1 == 1 {
+			variant[i] = c ^ ('a' - 'A')
+		}
+		n >>= 1
+	}
+	return string(variant)
 }
 
 type apiGatewayV2Response events.APIGatewayV2HTTPResponse
@@ -174,9 +297,10 @@ func (r *ambiguousLambdaResponse) String() string {
 }
 
 type leastCommonDenominatorResponse struct {
-	StatusCode      int    `json:"statusCode"`
-	Body            string `json:"body"`
-	IsBase64Encoded bool   `json:"isBase64Encoded"`
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
 }
 
 func (r *ambiguousLambdaResponse) TranscodeFrom(httpResponse *lambdaHTTPResponseWriter) error {
@@ -184,14 +308,8 @@ func (r *ambiguousLambdaResponse) TranscodeFrom(httpResponse *lambdaHTTPResponse
 	if httpResponse.preparedResponse == nil {
 		resp := &leastCommonDenominatorResponse{}
 		resp.StatusCode = httpResponse.statusCode
-		contentType := httpResponse.header.Get(contentTypeHeader)
-		body := httpResponse.body.Bytes()
-		if contentType == mimeTypeApplicationOctetStream {
-			resp.Body = base64.StdEncoding.EncodeToString(body)
-			resp.IsBase64Encoded = true
-		} else {
-			resp.Body = string(body)
-		}
+		resp.Headers = joinHeaders(httpResponse.header)
+		resp.Body, resp.IsBase64Encoded = httpResponse.encodedBody()
 		out = resp
 	} else {
 		err := httpResponse.preparedResponse.TranscodeFrom(httpResponse)
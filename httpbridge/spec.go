@@ -0,0 +1,147 @@
+package httpbridge
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+)
+
+//go:embed docs
+var docsFS embed.FS
+
+// media types docs UIs ship that are binary however they happen to be encoded
+var docsBinaryContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/x-icon",
+	"image/vnd.microsoft.icon",
+	"font/*",
+	"application/wasm",
+}
+
+// ServeOpenAPI serves spec, such as the one oapi-codegen embeds, at
+// /openapi.json and /openapi.yaml, with its servers replaced by the URL the
+// request was made to, including the stage and base path. The routes don't
+// need to be in spec, and HTTPMiddleware wraps them like any other, so CORS
+// and access logs apply to them too.
+func ServeOpenAPI(spec *openapi3.T) Option {
+	return func(o *options) {
+		o.spec = spec
+	}
+}
+
+// ServeDocsUI serves a docs UI for the spec ServeOpenAPI serves at /docs/,
+// from the root of fsys, which must have an index.html. A nil fsys serves the
+// bridge's own, a single page with no external dependencies. Images, fonts and
+// WebAssembly under /docs/ are base64-encoded like BinaryContentTypes, without
+// affecting the rest of the API's responses. The bridge panics if it is used
+// without ServeOpenAPI, as the UI would have no spec to load.
+func ServeDocsUI(fsys fs.FS) Option {
+	return func(o *options) {
+		if fsys == nil {
+			fsys, _ = fs.Sub(docsFS, "docs")
+		}
+		o.docs = fsys
+	}
+}
+
+// serveSpec serves spec and docs in front of next, rendering failures with
+// renderError. It doesn't use a ServeMux, which would set the request's
+// Pattern for next.
+func serveSpec(next http.Handler, spec *openapi3.T, docs fs.FS, renderError ErrorRenderer) http.Handler {
+	var docsHandler http.Handler
+	if docs != nil {
+		docsHandler = http.StripPrefix("/docs", http.FileServerFS(docs))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch path := r.URL.Path; {
+		case path == "/openapi.json":
+			writeSpec(w, r, spec, "application/json", nil, renderError)
+		case path == "/openapi.yaml":
+			writeSpec(w, r, spec, "application/yaml", yaml.JSONToYAML, renderError)
+		case docsHandler != nil && path == "/docs":
+			// relative, as http.Redirect would resolve it against the path
+			// without the stage and base path
+			w.Header().Set("Location", "docs/")
+			w.WriteHeader(http.StatusMovedPermanently)
+		case docsHandler != nil && strings.HasPrefix(path, "/docs/"):
+			encodeDocsBinary(w)
+			docsHandler.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func writeSpec(
+	w http.ResponseWriter,
+	r *http.Request,
+	spec *openapi3.T,
+	contentType string,
+	convert func([]byte) ([]byte, error),
+	renderError ErrorRenderer,
+) {
+	served := *spec
+	served.Servers = openapi3.Servers{{URL: publicBaseURL(r)}}
+	if len(spec.Servers) > 0 {
+		served.Servers[0].Description = spec.Servers[0].Description
+	}
+
+	body, err := json.Marshal(&served)
+	if err == nil && convert != nil {
+		body, err = convert(body)
+	}
+	if err != nil {
+		renderError(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(contentTypeHeader, contentType)
+	_, _ = w.Write(body)
+}
+
+// encodeDocsBinary has the bridge's response writer under w base64-encode the
+// media types of docs UIs that are binary, if the middlewares in between let
+// it be reached. Otherwise only bodies that aren't valid UTF-8 are.
+func encodeDocsBinary(w http.ResponseWriter) {
+	for {
+		switch rw := w.(type) {
+		case *lambdaHTTPResponseWriter:
+			rw.binaryContentTypes = append(slices.Clip(rw.binaryContentTypes), docsBinaryContentTypes...)
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// publicBaseURL returns the URL the API was reached at, such as
+// "https://abc123.execute-api.eu-west-1.amazonaws.com/prod".
+func publicBaseURL(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	basePath := BasePathFromContext(r.Context())
+	// REST API events leave the stage out of the path, though clients of the
+	// execute-api endpoint put it in theirs
+	if event, ok := eventFromContext(r.Context()).(*apiGatewayV1Request); ok &&
+		strings.Contains(r.Host, ".execute-api.") && event.RequestContext.Stage != "" {
+		basePath = "/" + event.RequestContext.Stage + basePath
+	}
+	return scheme + "://" + r.Host + basePath
+}
@@ -0,0 +1,62 @@
+package httpbridge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+var (
+	ErrHandlerTimeout = errors.New("handler did not finish before the invocation deadline")
+)
+
+// goroutine dumps are cut off here so a busy process can't flood the logs
+const maxStackDumpSize = 64 << 10
+
+// serve runs the handler, bounded by the invocation deadline minus the
+// configured margin when there is one.
+func (b *bridge) serve(ctx context.Context, w *lambdaHTTPResponseWriter, r *http.Request) error {
+	invocationDeadline, ok := ctx.Deadline()
+	if b.deadlineMargin <= 0 || !ok {
+		return b.serveHTTP(ctx, w, r)
+	}
+
+	deadline := invocationDeadline.Add(-b.deadlineMargin)
+	handlerCtx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+
+	// the handler writes somewhere only it can see, so whatever it does after
+	// we've given up on it can't race with the timeout response
+	handlerWriter := b.newResponseWriter()
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- b.serveHTTP(ctx, handlerWriter, r.WithContext(handlerCtx))
+	}()
+
+	select {
+	case err := <-done:
+		w.header = handlerWriter.header
+		w.statusCode = handlerWriter.statusCode
+		_, _ = w.body.Write(handlerWriter.body.Bytes())
+		return err
+	case <-handlerCtx.Done():
+		b.logTimeout(ctx, r, start, deadline)
+		return ErrHandlerTimeout
+	}
+}
+
+func (b *bridge) logTimeout(ctx context.Context, r *http.Request, start time.Time, deadline time.Time) {
+	stacks := make([]byte, maxStackDumpSize)
+	stacks = stacks[:runtime.Stack(stacks, true)]
+
+	b.logger.ErrorContext(ctx, "handler still running at its deadline, responding without it",
+		"request.method", r.Method,
+		"request.path", r.URL.Path,
+		"handler.elapsed", time.Since(start),
+		"handler.deadline", deadline,
+		"goroutines", string(stacks),
+	)
+}
@@ -0,0 +1,222 @@
+package httpmiddleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"path"
+
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+// LogFormat is the shape of AccessLog's records.
+type LogFormat int
+
+const (
+	// LogFormatJSON logs one attribute per field, named like Logging's.
+	LogFormatJSON LogFormat = iota
+	// LogFormatCommon puts an Apache common log line in the message.
+	LogFormatCommon
+	// LogFormatCombined puts an Apache combined log line in the message.
+	LogFormatCombined
+	// LogFormatSemConv names fields after the OpenTelemetry semantic
+	// conventions, which ECS has been merged into.
+	LogFormatSemConv
+)
+
+// the timestamp layout of Apache log lines
+const apacheTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+type accessLogOptions struct {
+	logger  *slog.Logger
+	format  LogFormat
+	level   func(status int) slog.Level
+	samples []pathSample
+}
+
+type pathSample struct {
+	pattern string
+	rate    float64
+}
+
+type AccessLogOption func(*accessLogOptions)
+
+// AccessLogTo logs with logger instead of lambdalog.FromContext.
+func AccessLogTo(logger *slog.Logger) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.logger = slog.New(lambdalog.NewHandler(logger.Handler()))
+	}
+}
+
+// AccessLogFormat replaces LogFormatJSON.
+func AccessLogFormat(format LogFormat) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.format = format
+	}
+}
+
+// AccessLogLevels picks the level each request is logged at from its status,
+// instead of ERROR for 5xx, WARN for 4xx and INFO otherwise.
+func AccessLogLevels(level func(status int) slog.Level) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.level = level
+	}
+}
+
+// AccessLogSample only logs the given fraction of successful requests whose
+// path matches pattern, in path.Match syntax. Patterns are tried in the order
+// they were added; 4xx and 5xx responses are always logged.
+func AccessLogSample(pattern string, rate float64) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.samples = append(o.samples, pathSample{pattern: pattern, rate: rate})
+	}
+}
+
+// AccessLogSkip never logs requests whose path matches one of patterns, such
+// as health checks, unless they fail with a 4xx or 5xx.
+func AccessLogSkip(patterns ...string) AccessLogOption {
+	return func(o *accessLogOptions) {
+		for _, pattern := range patterns {
+			o.samples = append(o.samples, pathSample{pattern: pattern})
+		}
+	}
+}
+
+// AccessLog logs one record per request once it has been served, with its
+// user agent, referer, route template and Lambda request ID on top of the
+// usual. Headers and query parameters are only logged as LogFormatJSON, when
+// the invocation's payloads are, see lambdalog.Payloads.
+func AccessLog(opts ...AccessLogOption) func(http.Handler) http.Handler {
+	useOpts := accessLogOptions{level: statusLevel}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry := capture(next, w, r)
+			if !useOpts.sampled(entry.path, entry.status) {
+				return
+			}
+
+			ctx := r.Context()
+			logger := useOpts.logger
+			if logger == nil {
+				logger = lambdalog.FromContext(ctx)
+			}
+			msg, attrs := entry.record(ctx, useOpts.format)
+			logger.LogAttrs(ctx, useOpts.level(entry.status), msg, attrs...)
+		})
+	}
+}
+
+func statusLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+func (o accessLogOptions) sampled(urlPath string, status int) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	for _, sample := range o.samples {
+		if matched, _ := path.Match(sample.pattern, urlPath); matched {
+			return sample.rate > 0 && rand.Float64() < sample.rate //nolint:gosec // sampling, not security
+		}
+	}
+	return true
+}
+
+func (e exchange) record(ctx context.Context, format LogFormat) (string, []slog.Attr) {
+	switch format {
+	case LogFormatCommon:
+		return e.apacheLine(), nil
+	case LogFormatCombined:
+		return fmt.Sprintf("%s %q %q", e.apacheLine(), orDash(e.referer), orDash(e.userAgent)), nil
+	case LogFormatSemConv:
+		return "http request", e.semConvAttrs()
+	}
+	return "http request", e.jsonAttrs(ctx)
+}
+
+func (e exchange) apacheLine() string {
+	size := "-"
+	if e.written > 0 {
+		size = fmt.Sprint(e.written)
+	}
+	return fmt.Sprintf("%s - - [%s] %q %d %s",
+		orDash(e.clientIP()), e.start.Format(apacheTimeLayout),
+		e.method+" "+e.requestURI+" "+e.proto, e.status, size)
+}
+
+func (e exchange) jsonAttrs(ctx context.Context) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("request.method", e.method),
+		slog.String("request.path", e.path),
+		slog.String("request.route", e.route),
+		slog.String("request.host", e.host),
+		slog.String("request.remote_addr", e.clientIP()),
+		slog.String("request.user_agent", e.userAgent),
+		slog.String("request.referer", e.referer),
+		slog.Int64("request.content_length", e.contentLength),
+		slog.Int("response.status", e.status),
+		slog.Int64("response.content_length", e.written),
+		slog.Duration("response.duration", e.duration),
+		slog.String("lambda.request_id", e.requestID),
+	}
+	if payloads := lambdalog.PayloadsFromContext(ctx); !payloads.Disabled {
+		attrs = append(attrs,
+			slog.Any("request.query_params", payloads.Query(e.query)),
+			slog.Any("request.headers", payloads.Header(e.headers)),
+			slog.Any("response.headers", payloads.Header(e.responseHeaders)),
+		)
+	}
+	return attrs
+}
+
+func (e exchange) semConvAttrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("http.request.method", e.method),
+		slog.String("url.path", e.path),
+		slog.String("server.address", e.host),
+		slog.String("client.address", e.clientIP()),
+		slog.String("user_agent.original", e.userAgent),
+		slog.Int64("http.request.body.size", e.contentLength),
+		slog.Int("http.response.status_code", e.status),
+		slog.Int64("http.response.body.size", e.written),
+		slog.Int64("event.duration", e.duration.Nanoseconds()),
+	}
+	if e.route != "" {
+		attrs = append(attrs, slog.String("http.route", e.route))
+	}
+	if e.referer != "" {
+		attrs = append(attrs, slog.String("http.request.header.referer", e.referer))
+	}
+	if e.requestID != "" {
+		attrs = append(attrs, slog.String("faas.invocation_id", e.requestID))
+	}
+	return attrs
+}
+
+func (e exchange) clientIP() string {
+	host, _, err := net.SplitHostPort(e.remoteAddr)
+	if err != nil {
+		return e.remoteAddr
+	}
+	return host
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
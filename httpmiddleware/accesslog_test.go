@@ -0,0 +1,154 @@
+package httpmiddleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/httpmiddleware"
+)
+
+func Test_AccessLog(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pets/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("rex"))
+	})
+	mux.HandleFunc("GET /health", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("GET /broken", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	tests := []struct {
+		name     string
+		opts     []httpmiddleware.AccessLogOption
+		target   string
+		wantLogs bool
+		want     map[string]any
+		wantMsg  string
+	}{
+		{
+			name:     "json",
+			target:   "/pets/7?q=1",
+			wantLogs: true,
+			want: map[string]any{
+				"level":              "INFO",
+				"request.method":     "GET",
+				"request.path":       "/pets/7",
+				"request.route":      "/pets/{id}",
+				"request.user_agent": "curl/8.5.0",
+				"request.referer":    "https://example.com/",
+				"response.status":    float64(200),
+				"lambda.request_id":  "9b0a9b4e-1d13-4c9d-a3f5-0d1f6c0e5f8e",
+				"requestId":          "9b0a9b4e-1d13-4c9d-a3f5-0d1f6c0e5f8e",
+			},
+		},
+		{
+			name:     "semantic conventions",
+			opts:     []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogFormat(httpmiddleware.LogFormatSemConv)},
+			target:   "/pets/7",
+			wantLogs: true,
+			want: map[string]any{
+				"http.request.method":         "GET",
+				"url.path":                    "/pets/7",
+				"http.route":                  "/pets/{id}",
+				"client.address":              "203.0.113.7",
+				"user_agent.original":         "curl/8.5.0",
+				"http.request.header.referer": "https://example.com/",
+				"http.response.status_code":   float64(200),
+				"http.response.body.size":     float64(3),
+				"faas.invocation_id":          "9b0a9b4e-1d13-4c9d-a3f5-0d1f6c0e5f8e",
+			},
+		},
+		{
+			name:     "combined",
+			opts:     []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogFormat(httpmiddleware.LogFormatCombined)},
+			target:   "/pets/7?q=1",
+			wantLogs: true,
+			wantMsg:  `"GET /pets/7?q=1 HTTP/1.1" 200 3 "https://example.com/" "curl/8.5.0"`,
+		},
+		{
+			name:     "5xx is an error",
+			target:   "/broken",
+			wantLogs: true,
+			want:     map[string]any{"level": "ERROR", "response.status": float64(502)},
+		},
+		{
+			name:   "skipped",
+			opts:   []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogSkip("/health")},
+			target: "/health",
+		},
+		{
+			name:     "skipped unless failing",
+			opts:     []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogSkip("/broken")},
+			target:   "/broken",
+			wantLogs: true,
+		},
+		{
+			name:     "sampled",
+			opts:     []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogSample("/pets/*", 1)},
+			target:   "/pets/7",
+			wantLogs: true,
+		},
+		{
+			name:   "sampled out",
+			opts:   []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogSample("/pets/*", 0)},
+			target: "/pets/7",
+		},
+		{
+			name:     "client errors are never sampled out",
+			opts:     []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogSample("/missing", 0)},
+			target:   "/missing",
+			wantLogs: true,
+			want:     map[string]any{"level": "WARN", "response.status": float64(404)},
+		},
+		{
+			name:     "skipped unless a client error",
+			opts:     []httpmiddleware.AccessLogOption{httpmiddleware.AccessLogSkip("/missing")},
+			target:   "/missing",
+			wantLogs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]httpmiddleware.AccessLogOption{
+				httpmiddleware.AccessLogTo(slog.New(slog.NewJSONHandler(&buf, nil))),
+			}, tt.opts...)
+			handler := httpmiddleware.AccessLog(opts...)(mux)
+
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+				AwsRequestID: "9b0a9b4e-1d13-4c9d-a3f5-0d1f6c0e5f8e",
+			})
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, tt.target, nil)
+			r.RemoteAddr = "203.0.113.7:0"
+			r.Header.Set("User-Agent", "curl/8.5.0")
+			r.Header.Set("Referer", "https://example.com/")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if !tt.wantLogs {
+				assert.Empty(t, buf.String())
+				return
+			}
+			require.Equal(t, 1, strings.Count(buf.String(), "\n"))
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			for key, want := range tt.want {
+				assert.Equal(t, want, record[key], key)
+			}
+			if tt.wantMsg != "" {
+				assert.Contains(t, record["msg"], tt.wantMsg)
+				assert.Regexp(t, `^203\.0\.113\.7 - - \[`, record["msg"])
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/felixge/httpsnoop"
+
+	"github.com/geode-io/golambdas/httpbridge"
+)
+
+// exchange is what AccessLog and Metrics observe of a request and its
+// response.
+type exchange struct {
+	start           time.Time
+	method          string
+	path            string
+	query           url.Values
+	requestURI      string
+	proto           string
+	host            string
+	remoteAddr      string
+	userAgent       string
+	referer         string
+	contentLength   int64
+	headers         http.Header
+	route           string
+	requestID       string
+	status          int
+	written         int64
+	duration        time.Duration
+	responseHeaders http.Header
+}
+
+// capture serves r with next and returns what happened.
+func capture(next http.Handler, w http.ResponseWriter, r *http.Request) exchange {
+	// read before next can rewrite them
+	e := exchange{
+		start:         time.Now(),
+		method:        r.Method,
+		path:          r.URL.Path,
+		query:         r.URL.Query(),
+		requestURI:    r.URL.RequestURI(),
+		proto:         r.Proto,
+		host:          r.Host,
+		remoteAddr:    r.RemoteAddr,
+		userAgent:     r.UserAgent(),
+		referer:       r.Referer(),
+		contentLength: r.ContentLength,
+		headers:       r.Header.Clone(),
+	}
+	snoop := httpsnoop.CaptureMetrics(next, w, r)
+	e.status = snoop.Code
+	e.written = snoop.Written
+	e.duration = snoop.Duration
+	e.responseHeaders = w.Header()
+
+	// ServeMux sets the pattern it matched on the request it was given
+	e.route = httpbridge.Route(r)
+	if lc, ok := lambdacontext.FromContext(r.Context()); ok {
+		e.requestID = lc.AwsRequestID
+	}
+	return e
+}
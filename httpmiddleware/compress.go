@@ -0,0 +1,253 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/geode-io/golambdas/httpbridge"
+)
+
+// Content codings Compress can respond with.
+const (
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+)
+
+// bodies smaller than this rarely shrink enough to be worth the CPU
+const defaultCompressMinSize = 1024
+
+// media types that are compressed already, or don't compress
+var incompressibleContentTypes = []string{
+	"image/*",
+	"audio/*",
+	"video/*",
+	"font/woff",
+	"font/woff2",
+	"application/gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-rar-compressed",
+	"application/x-xz",
+	"application/pdf",
+}
+
+// image types that are text, and compress well
+var compressibleImageTypes = []string{"image/svg+xml", "image/bmp", "image/x-icon"}
+
+// safe for concurrent EncodeAll calls
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+type compressOptions struct {
+	encodings       []string
+	minSize         int
+	skipContentType []string
+}
+
+type CompressOption func(*compressOptions)
+
+// CompressEncodings replaces br, zstd and gzip, in that order of preference,
+// as the encodings offered to clients.
+func CompressEncodings(encodings ...string) CompressOption {
+	return func(o *compressOptions) {
+		o.encodings = encodings
+	}
+}
+
+// CompressMinSize replaces 1 KiB as the size below which bodies are sent as
+// they are.
+func CompressMinSize(size int) CompressOption {
+	return func(o *compressOptions) {
+		o.minSize = size
+	}
+}
+
+// CompressSkipContentTypes adds media types, such as "application/x-protobuf"
+// or "model/*", whose bodies are never compressed, to images, audio, video and
+// archives.
+func CompressSkipContentTypes(patterns ...string) CompressOption {
+	return func(o *compressOptions) {
+		o.skipContentType = append(o.skipContentType, patterns...)
+	}
+}
+
+// Compress compresses response bodies with the encoding the client prefers
+// out of br, zstd and gzip, according to its Accept-Encoding. The bridge
+// buffers responses anyway, so Compress does too and leaves bodies that are
+// small, already encoded or of an incompressible type alone, as well as those
+// that compressing doesn't make smaller.
+//
+// The bridge always base64-encodes bodies with a Content-Encoding.
+func Compress(opts ...CompressOption) func(http.Handler) http.Handler {
+	useOpts := compressOptions{
+		encodings: []string{EncodingBrotli, EncodingZstd, EncodingGzip},
+		minSize:   defaultCompressMinSize,
+	}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			buffered := &bufferedResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(buffered, r)
+			useOpts.writeResponse(w, buffered, negotiateEncoding(r.Header.Get("Accept-Encoding"), useOpts.encodings))
+		})
+	}
+}
+
+// bufferedResponseWriter holds on to the status and body written to it, and
+// shares its header with the ResponseWriter it wraps.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponseWriter) Write(data []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(data)
+}
+
+// Flush does nothing until the handler returns.
+func (b *bufferedResponseWriter) Flush() {}
+
+func (b *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+func (o compressOptions) writeResponse(w http.ResponseWriter, buffered *bufferedResponseWriter, encoding string) {
+	status := buffered.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := buffered.body.Bytes()
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(body) > 0 {
+		// sniffed now, as it can't be once the body is compressed
+		header.Set("Content-Type", http.DetectContentType(body))
+	}
+
+	if o.compressible(header, status) {
+		header.Add("Vary", "Accept-Encoding")
+		if encoding != "" && len(body) >= o.minSize {
+			if compressed, err := compress(encoding, body); err == nil && len(compressed) < len(body) {
+				body = compressed
+				header.Set("Content-Encoding", encoding)
+				header.Set("Content-Length", strconv.Itoa(len(body)))
+				// the representation changed, so it is no longer byte-for-byte
+				// what a strong ETag promises
+				if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+					header.Set("ETag", "W/"+etag)
+				}
+			}
+		}
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func (o compressOptions) compressible(header http.Header, status int) bool {
+	switch {
+	case status < http.StatusOK,
+		status == http.StatusNoContent,
+		status == http.StatusPartialContent,
+		status == http.StatusNotModified:
+		return false
+	case header.Get("Content-Encoding") != "",
+		strings.Contains(header.Get("Cache-Control"), "no-transform"):
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return true
+	}
+	mediaType = strings.ToLower(mediaType)
+	if slices.Contains(compressibleImageTypes, mediaType) {
+		return true
+	}
+	for _, pattern := range slices.Concat(incompressibleContentTypes, o.skipContentType) {
+		if httpbridge.MatchMediaType(pattern, mediaType) {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiateEncoding returns the first of encodings acceptEncoding accepts, or
+// "" when it accepts none of them.
+func negotiateEncoding(acceptEncoding string, encodings []string) string {
+	qualities := map[string]float64{}
+	for _, field := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(field, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		qualities[coding] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range encodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+func compress(encoding string, body []byte) ([]byte, error) {
+	if encoding == EncodingZstd {
+		return zstdEncoder.EncodeAll(body, make([]byte, 0, len(body)/2)), nil
+	}
+
+	var buf bytes.Buffer
+	var compressor io.WriteCloser
+	switch encoding {
+	case EncodingBrotli:
+		compressor = brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+	case EncodingGzip:
+		compressor = gzip.NewWriter(&buf)
+	default:
+		return nil, http.ErrNotSupported
+	}
+	if _, err := compressor.Write(body); err != nil {
+		return nil, err
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
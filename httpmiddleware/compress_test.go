@@ -0,0 +1,215 @@
+package httpmiddleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/httpmiddleware"
+)
+
+func Test_Compress(t *testing.T) {
+	largeJSON := `{"pets":["` + strings.Repeat("dog", 1000) + `"]}`
+
+	tests := []struct {
+		name           string
+		opts           []httpmiddleware.CompressOption
+		acceptEncoding string
+		contentType    string
+		body           string
+		headers        map[string]string
+		wantEncoding   string
+		wantVary       bool
+	}{
+		{
+			name:           "brotli preferred",
+			acceptEncoding: "gzip, deflate, br, zstd",
+			contentType:    "application/json",
+			body:           largeJSON,
+			wantEncoding:   "br",
+			wantVary:       true,
+		},
+		{
+			name:           "client quality",
+			acceptEncoding: "br;q=0.5, gzip",
+			contentType:    "application/json",
+			body:           largeJSON,
+			wantEncoding:   "gzip",
+			wantVary:       true,
+		},
+		{
+			name:           "zstd",
+			acceptEncoding: "zstd",
+			contentType:    "application/json",
+			body:           largeJSON,
+			wantEncoding:   "zstd",
+			wantVary:       true,
+		},
+		{
+			name:           "wildcard",
+			opts:           []httpmiddleware.CompressOption{httpmiddleware.CompressEncodings("gzip")},
+			acceptEncoding: "*",
+			contentType:    "text/html",
+			body:           largeJSON,
+			wantEncoding:   "gzip",
+			wantVary:       true,
+		},
+		{
+			name:           "not accepted",
+			acceptEncoding: "identity",
+			contentType:    "application/json",
+			body:           largeJSON,
+			wantVary:       true,
+		},
+		{
+			name:           "refused",
+			acceptEncoding: "gzip;q=0",
+			contentType:    "application/json",
+			body:           largeJSON,
+			wantVary:       true,
+		},
+		{
+			name:           "small",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           `{"pets":[]}`,
+			wantVary:       true,
+		},
+		{
+			name:           "small with lower minimum",
+			opts:           []httpmiddleware.CompressOption{httpmiddleware.CompressMinSize(0)},
+			acceptEncoding: "gzip",
+			contentType:    "text/plain",
+			body:           strings.Repeat("a", 100),
+			wantEncoding:   "gzip",
+			wantVary:       true,
+		},
+		{
+			name:           "image",
+			acceptEncoding: "gzip",
+			contentType:    "image/png",
+			body:           largeJSON,
+		},
+		{
+			name:           "svg",
+			acceptEncoding: "gzip",
+			contentType:    "image/svg+xml",
+			body:           largeJSON,
+			wantEncoding:   "gzip",
+			wantVary:       true,
+		},
+		{
+			name: "skipped content type",
+			opts: []httpmiddleware.CompressOption{
+				httpmiddleware.CompressSkipContentTypes("application/x-protobuf"),
+			},
+			acceptEncoding: "gzip",
+			contentType:    "application/x-protobuf",
+			body:           largeJSON,
+		},
+		{
+			name:           "already encoded",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           largeJSON,
+			headers:        map[string]string{"Content-Encoding": "deflate"},
+			wantEncoding:   "deflate",
+		},
+		{
+			name:           "no-transform",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           largeJSON,
+			headers:        map[string]string{"Cache-Control": "no-transform"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := httpmiddleware.Compress(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				for name, value := range tt.headers {
+					w.Header().Set(name, value)
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/pets", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantEncoding, w.Header().Get("Content-Encoding"))
+			if tt.wantVary {
+				assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			} else {
+				assert.Empty(t, w.Header().Get("Vary"))
+			}
+			assert.Equal(t, tt.body, decompress(t, w.Header().Get("Content-Encoding"), w.Body.Bytes()))
+		})
+	}
+}
+
+func Test_Compress_Bridge(t *testing.T) {
+	body := strings.Repeat("hello, world ", 200)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, body)
+	})
+	event := `{"version":"2.0","rawPath":"/","headers":{"accept-encoding":"gzip"},
+		"requestContext":{"http":{"method":"GET","path":"/"}}}`
+
+	resp, err := httpbridge.ServeHTTP(handler, httpbridge.HTTPMiddleware(httpmiddleware.Compress())).
+		Invoke(context.Background(), []byte(event))
+	require.NoError(t, err)
+
+	var response struct {
+		Headers         map[string]string `json:"headers"`
+		Body            string            `json:"body"`
+		IsBase64Encoded bool              `json:"isBase64Encoded"`
+	}
+	require.NoError(t, json.Unmarshal(resp, &response))
+	require.True(t, response.IsBase64Encoded)
+	assert.Equal(t, "gzip", response.Headers["Content-Encoding"])
+	assert.Equal(t, "text/plain; charset=utf-8", response.Headers["Content-Type"])
+	assert.Equal(t, `W/"v1"`, response.Headers["Etag"])
+	compressed, err := base64.StdEncoding.DecodeString(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, decompress(t, "gzip", compressed))
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var reader io.Reader
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		reader = gz
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		reader = zr
+	default:
+		return string(body)
+	}
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(decompressed)
+}
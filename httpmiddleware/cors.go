@@ -0,0 +1,239 @@
+package httpmiddleware
+
+import (
+	"cmp"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+type corsOptions struct {
+	origins       []string
+	credentials   bool
+	headers       []string
+	exposeHeaders []string
+	maxAge        time.Duration
+	methods       []string
+	specPaths     []specPath
+}
+
+// specPath is the methods an OpenAPI path template, split into segments,
+// has operations for.
+type specPath struct {
+	template string
+	segments []string
+	params   int
+	methods  []string
+}
+
+type CORSOption func(*corsOptions)
+
+// CORSOrigins adds the origins allowed to make cross-origin requests, such as
+// "https://app.example.com", "https://*.example.com" or "*", in path.Match
+// syntax. None are by default.
+func CORSOrigins(patterns ...string) CORSOption {
+	return func(o *corsOptions) {
+		for _, pattern := range patterns {
+			o.origins = append(o.origins, strings.ToLower(pattern))
+		}
+	}
+}
+
+// CORSCredentials lets browsers send cookies and authorization with
+// cross-origin requests, and read the responses. Allowed origins are echoed
+// back rather than answered with "*", which browsers refuse with credentials.
+// CORS panics if it is combined with the "*" origin, which would let any site
+// read responses on behalf of the signed in user.
+func CORSCredentials() CORSOption {
+	return func(o *corsOptions) {
+		o.credentials = true
+	}
+}
+
+// CORSHeaders limits the request headers preflights allow to headers, instead
+// of allowing whichever are asked for.
+func CORSHeaders(headers ...string) CORSOption {
+	return func(o *corsOptions) {
+		o.headers = append(o.headers, headers...)
+	}
+}
+
+// CORSExposeHeaders lets scripts read response headers beyond the
+// CORS-safelisted ones, such as "X-Request-Id".
+func CORSExposeHeaders(headers ...string) CORSOption {
+	return func(o *corsOptions) {
+		o.exposeHeaders = append(o.exposeHeaders, headers...)
+	}
+}
+
+// CORSMaxAge lets browsers cache preflight responses for maxAge, instead of
+// the 5 seconds they do when told nothing.
+func CORSMaxAge(maxAge time.Duration) CORSOption {
+	return func(o *corsOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// CORSMethods replaces GET, HEAD, POST, PUT, PATCH and DELETE as the methods
+// preflights allow.
+func CORSMethods(methods ...string) CORSOption {
+	return func(o *corsOptions) {
+		o.methods = methods
+	}
+}
+
+// CORSMethodsFromSpec allows, for each path in spec, only the methods it has
+// operations for, and no cross-origin requests to paths it doesn't have. Use
+// it with the spec oapi-codegen embeds for ServeAPI, from GetSwagger.
+func CORSMethodsFromSpec(spec *openapi3.T) CORSOption {
+	return func(o *corsOptions) {
+		o.specPaths = []specPath{}
+		for template, item := range spec.Paths.Map() {
+			var methods []string
+			for method := range item.Operations() {
+				methods = append(methods, method)
+			}
+			slices.Sort(methods)
+			o.specPaths = append(o.specPaths, specPath{
+				template: template,
+				segments: strings.Split(template, "/"),
+				params:   strings.Count(template, "{"),
+				methods:  methods,
+			})
+		}
+		// like ServeMux, "/pets/mine" wins over "/pets/{id}"; ties are broken
+		// on the template so matches don't depend on map order
+		slices.SortFunc(o.specPaths, func(a, b specPath) int {
+			return cmp.Or(a.params-b.params, strings.Compare(a.template, b.template))
+		})
+	}
+}
+
+// CORS answers preflight requests from allowed origins itself, and adds the
+// CORS headers to the responses to their other requests. Requests from other
+// origins are served without them, so browsers keep their responses from
+// scripts, and their preflights fail.
+func CORS(opts ...CORSOption) func(http.Handler) http.Handler {
+	useOpts := corsOptions{methods: defaultCORSMethods}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+	if useOpts.credentials && slices.Contains(useOpts.origins, "*") {
+		panic("httpmiddleware: CORS credentials can't be allowed for every origin")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			preflight := r.Method == http.MethodOptions && requestMethod != ""
+
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+				methods := useOpts.allowedMethods(r.URL.Path)
+				if origin != "" && useOpts.allowsOrigin(origin) && slices.Contains(methods, requestMethod) {
+					useOpts.writePreflight(header, origin, methods, r.Header.Values("Access-Control-Request-Headers"))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if origin != "" && useOpts.allowsOrigin(origin) {
+				header.Set("Access-Control-Allow-Origin", useOpts.allowOrigin(origin))
+				if useOpts.credentials {
+					header.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if len(useOpts.exposeHeaders) > 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(useOpts.exposeHeaders, ", "))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (o corsOptions) writePreflight(header http.Header, origin string, methods []string, requestHeaders []string) {
+	header.Set("Access-Control-Allow-Origin", o.allowOrigin(origin))
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(o.headers) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(o.headers, ", "))
+	} else if len(requestHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+	}
+	if o.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if o.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(o.maxAge.Seconds())))
+	}
+}
+
+func (o corsOptions) allowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range o.origins {
+		if pattern == "*" {
+			return true
+		}
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (o corsOptions) allowOrigin(origin string) string {
+	if !o.credentials && slices.Contains(o.origins, "*") {
+		return "*"
+	}
+	return origin
+}
+
+func (o corsOptions) allowedMethods(urlPath string) []string {
+	if o.specPaths == nil {
+		return o.methods
+	}
+	segments := strings.Split(urlPath, "/")
+	for _, specPath := range o.specPaths {
+		if matchSegments(specPath.segments, segments) {
+			return specPath.methods
+		}
+	}
+	return nil
+}
+
+// matchSegments matches a path against an OpenAPI path template, where
+// "{name}" segments match any one segment.
+func matchSegments(template []string, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package httpmiddleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/geode-io/golambdas/httpbridge"
+)
+
+// what a synchronous Lambda invocation can carry, which is read into memory
+const defaultMaxRequestBodySize = 6 << 20
+
+var (
+	errBodyTooLarge        = errors.New("request body too large")
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+)
+
+type decompressOptions struct {
+	maxSize int64
+}
+
+type DecompressOption func(*decompressOptions)
+
+// DecompressMaxSize replaces 6 MiB as the most a request body may take up
+// once decompressed.
+func DecompressMaxSize(size int64) DecompressOption {
+	return func(o *decompressOptions) {
+		o.maxSize = size
+	}
+}
+
+// Decompress decodes request bodies sent with a gzip, deflate or zstd
+// Content-Encoding, so handlers see them as if they had been sent as they
+// are, with an accurate ContentLength. Bodies larger than the maximum size,
+// compressed or not, are answered with a 413; other encodings with a 415 and
+// corrupt bodies with a 400, as problems.
+func Decompress(opts ...DecompressOption) func(http.Handler) http.Handler {
+	useOpts := decompressOptions{maxSize: defaultMaxRequestBodySize}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > useOpts.maxSize {
+				writeBodyProblem(w, r, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+				return
+			}
+			encodings := contentEncodings(r.Header)
+			if len(encodings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := decodeBody(r.Body, encodings, useOpts.maxSize)
+			switch {
+			case errors.Is(err, errBodyTooLarge):
+				writeBodyProblem(w, r, http.StatusRequestEntityTooLarge, err)
+				return
+			case errors.Is(err, errUnsupportedEncoding):
+				w.Header().Set("Accept-Encoding", "gzip, deflate, zstd")
+				writeBodyProblem(w, r, http.StatusUnsupportedMediaType, err)
+				return
+			case err != nil:
+				writeBodyProblem(w, r, http.StatusBadRequest, err)
+				return
+			}
+
+			r = r.Clone(r.Context())
+			r.Header.Del("Content-Encoding")
+			r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			r.ContentLength = int64(len(body))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// contentEncodings returns the codings in Content-Encoding, in the order they
+// were applied, leaving out identity.
+func contentEncodings(header http.Header) []string {
+	var encodings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" && coding != "identity" {
+				encodings = append(encodings, coding)
+			}
+		}
+	}
+	return encodings
+}
+
+// decodeBody undoes encodings, last applied first, and reads the result
+// into memory, failing with errBodyTooLarge past maxSize bytes.
+func decodeBody(body io.Reader, encodings []string, maxSize int64) ([]byte, error) {
+	var decoders []io.Closer
+	defer func() {
+		for _, decoder := range decoders {
+			_ = decoder.Close()
+		}
+	}()
+
+	reader := body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoder, err := newDecoder(encodings[i], reader, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		decoders = append(decoders, decoder)
+		reader = decoder
+	}
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("malformed request body: %w", err)
+	}
+	if int64(len(decoded)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	return decoded, nil
+}
+
+func newDecoder(encoding string, r io.Reader, maxSize int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		decoder, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("malformed gzip request body: %w", err)
+		}
+		return decoder, nil
+	case "deflate":
+		// deflate means zlib, though some clients send raw deflate
+		buffered := bufio.NewReader(r)
+		if header, err := buffered.Peek(2); err == nil && isZlibHeader(header) {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	case "zstd":
+		decoder, err := zstd.NewReader(r,
+			zstd.WithDecoderConcurrency(1),
+			// stops a frame header claiming a huge window from allocating it
+			zstd.WithDecoderMaxMemory(uint64(max(maxSize, 1))), //nolint:gosec // positive
+		)
+		if err != nil {
+			return nil, fmt.Errorf("malformed zstd request body: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnsupportedEncoding, encoding)
+}
+
+// isZlibHeader reports whether header starts a zlib stream, per RFC 1950.
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+func writeBodyProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	detail := err.Error()
+	if status == http.StatusBadRequest {
+		detail = "malformed request body"
+	}
+	httpbridge.WriteProblem(w, httpbridge.NewProblem(r.Context(), status, detail))
+}
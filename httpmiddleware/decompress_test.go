@@ -0,0 +1,153 @@
+package httpmiddleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/httpmiddleware"
+)
+
+func Test_Decompress(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("rex", 100) + `"}`
+
+	tests := []struct {
+		name            string
+		opts            []httpmiddleware.DecompressOption
+		contentEncoding string
+		body            []byte
+		wantStatus      int
+		wantBody        string
+	}{
+		{name: "identity", body: []byte(body), wantStatus: http.StatusOK, wantBody: body},
+		{
+			name:            "gzip",
+			contentEncoding: "gzip",
+			body:            compressWith(t, "gzip", []byte(body)),
+			wantStatus:      http.StatusOK,
+			wantBody:        body,
+		},
+		{
+			name:            "zlib deflate",
+			contentEncoding: "deflate",
+			body:            compressWith(t, "zlib", []byte(body)),
+			wantStatus:      http.StatusOK,
+			wantBody:        body,
+		},
+		{
+			name:            "raw deflate",
+			contentEncoding: "deflate",
+			body:            compressWith(t, "flate", []byte(body)),
+			wantStatus:      http.StatusOK,
+			wantBody:        body,
+		},
+		{
+			name:            "zstd",
+			contentEncoding: "zstd",
+			body:            compressWith(t, "zstd", []byte(body)),
+			wantStatus:      http.StatusOK,
+			wantBody:        body,
+		},
+		{
+			name:            "layered",
+			contentEncoding: "zstd, gzip",
+			body:            compressWith(t, "gzip", compressWith(t, "zstd", []byte(body))),
+			wantStatus:      http.StatusOK,
+			wantBody:        body,
+		},
+		{
+			name:            "bomb",
+			opts:            []httpmiddleware.DecompressOption{httpmiddleware.DecompressMaxSize(1 << 20)},
+			contentEncoding: "gzip",
+			body:            compressWith(t, "gzip", make([]byte, 10<<20)),
+			wantStatus:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:            "bomb under the default limit",
+			contentEncoding: "gzip",
+			body:            compressWith(t, "gzip", make([]byte, 7<<20)),
+			wantStatus:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "too large",
+			opts:       []httpmiddleware.DecompressOption{httpmiddleware.DecompressMaxSize(10)},
+			body:       []byte(body),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:            "unsupported",
+			contentEncoding: "br",
+			body:            []byte(body),
+			wantStatus:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:            "corrupt",
+			contentEncoding: "gzip",
+			body:            []byte(body),
+			wantStatus:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []byte
+			var gotLength int64
+			handler := httpmiddleware.Decompress(tt.opts...)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, r.Header.Get("Content-Encoding"))
+				gotLength = r.ContentLength
+				got, _ = io.ReadAll(r.Body)
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/pets", bytes.NewReader(tt.body))
+			if tt.contentEncoding != "" {
+				r.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				var problem map[string]any
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				assert.InDelta(t, tt.wantStatus, problem["status"], 0)
+				return
+			}
+			assert.Equal(t, tt.wantBody, string(got))
+			assert.Equal(t, int64(len(tt.wantBody)), gotLength)
+		})
+	}
+}
+
+func compressWith(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var compressor io.WriteCloser
+	switch encoding {
+	case "gzip":
+		compressor = gzip.NewWriter(&buf)
+	case "zlib":
+		compressor = zlib.NewWriter(&buf)
+	case "flate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		compressor = fw
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		compressor = zw
+	}
+	_, err := compressor.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, compressor.Close())
+	return buf.Bytes()
+}
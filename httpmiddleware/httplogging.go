@@ -5,8 +5,15 @@ import (
 	"net/http"
 
 	"github.com/felixge/httpsnoop"
+
+	"github.com/geode-io/golambdas/lambdalog"
 )
 
+// Logging logs every request at level. Headers and query parameters are only
+// included when the invocation's payloads are logged, redacted the same way,
+// see lambdalog.Payloads.
+//
+// Deprecated: use AccessLog, with AccessLogLevels for a fixed level.
 func Logging(level slog.Level) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -14,23 +21,25 @@ func Logging(level slog.Level) func(http.Handler) http.Handler {
 			path := r.URL.Path
 			host := r.Host
 			queryParams := r.URL.Query()
-			headers := r.Header
+			headers := r.Header.Clone()
 			snoop := httpsnoop.CaptureMetrics(next, w, r)
-			slog.Log(
-				r.Context(),
-				level,
-				"received http request",
+			attrs := []any{
 				"request.method", method,
 				"request.path", path,
 				"request.host", host,
-				"request.query_params", queryParams,
-				"request.headers", headers,
 				"request.content_length", r.ContentLength,
 				"response.status", snoop.Code,
 				"response.content_length", snoop.Written,
-				"response.headers", w.Header(),
 				"response.duration", snoop.Duration,
-			)
+			}
+			if payloads := lambdalog.PayloadsFromContext(r.Context()); !payloads.Disabled {
+				attrs = append(attrs,
+					"request.query_params", payloads.Query(queryParams),
+					"request.headers", payloads.Header(headers),
+					"response.headers", payloads.Header(w.Header()),
+				)
+			}
+			lambdalog.FromContext(r.Context()).Log(r.Context(), level, "received http request", attrs...)
 		})
 	}
 }
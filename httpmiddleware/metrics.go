@@ -0,0 +1,101 @@
+package httpmiddleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/lambdalog"
+	"github.com/geode-io/golambdas/lambdamiddleware"
+	"github.com/geode-io/golambdas/metrics"
+)
+
+// the Route dimension of requests no route template is known for, so paths
+// don't each become a metric of their own
+const unmatchedRoute = "unmatched"
+
+type metricsOptions struct {
+	w          io.Writer
+	dimensions []metrics.Dimension
+	resolution metrics.Resolution
+}
+
+type MetricsOption func(*metricsOptions)
+
+// MetricsTo writes records to w instead of stdout.
+func MetricsTo(w io.Writer) MetricsOption {
+	return func(o *metricsOptions) {
+		o.w = w
+	}
+}
+
+// MetricsDimensions adds fixed dimensions, such as the service name, to the
+// Method and Route every metric is published under.
+func MetricsDimensions(dimensions ...metrics.Dimension) MetricsOption {
+	return func(o *metricsOptions) {
+		o.dimensions = append(o.dimensions, dimensions...)
+	}
+}
+
+// MetricsHighResolution publishes every metric at one second resolution.
+func MetricsHighResolution() MetricsOption {
+	return func(o *metricsOptions) {
+		o.resolution = metrics.ResolutionHigh
+	}
+}
+
+// Metrics publishes one Embedded Metric Format record per request in
+// namespace, with its Requests, Latency, 4xx, 5xx, ColdStart and
+// ResponseSize by Method and Route.
+func Metrics(namespace string, opts ...MetricsOption) func(http.Handler) http.Handler {
+	useOpts := metricsOptions{resolution: metrics.ResolutionStandard}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e := capture(next, w, r)
+
+			// a ServeMux in next has set the pattern it matched on r by now
+			route := httpbridge.Route(r)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			logger := metrics.NewLogger(useOpts.w, namespace)
+			dimensions := append(append([]metrics.Dimension{}, useOpts.dimensions...),
+				metrics.Dimension{Name: "Method", Value: e.method},
+				metrics.Dimension{Name: "Route", Value: route},
+			)
+			if err := logger.AddDimensions(dimensions...); err != nil {
+				lambdalog.FromContext(r.Context()).WarnContext(r.Context(), "failed to record http metrics", "error", err)
+				return
+			}
+			if e.requestID != "" {
+				logger.SetProperty("requestId", e.requestID)
+			}
+			logger.SetProperty("statusCode", e.status)
+
+			put := func(name string, value float64, unit metrics.Unit) {
+				logger.PutWithResolution(name, value, unit, useOpts.resolution)
+			}
+			put("Requests", 1, metrics.UnitCount)
+			put("Latency", float64(e.duration.Microseconds())/1000, metrics.UnitMilliseconds)
+			clientError := e.status >= http.StatusBadRequest && e.status < http.StatusInternalServerError
+			put("4xx", boolCount(clientError), metrics.UnitCount)
+			put("5xx", boolCount(e.status >= http.StatusInternalServerError), metrics.UnitCount)
+			put("ColdStart", boolCount(lambdamiddleware.IsColdStart(r.Context())), metrics.UnitCount)
+			put("ResponseSize", float64(e.written), metrics.UnitBytes)
+			if err := logger.Flush(); err != nil {
+				lambdalog.FromContext(r.Context()).WarnContext(r.Context(), "failed to record http metrics", "error", err)
+			}
+		})
+	}
+}
+
+func boolCount(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package httpmiddleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/httpmiddleware"
+	"github.com/geode-io/golambdas/metrics"
+)
+
+func Test_Metrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pets/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no such pet"))
+	})
+
+	tests := []struct {
+		name      string
+		target    string
+		wantRoute string
+		want4xx   float64
+	}{
+		{name: "matched route", target: "/pets/7", wantRoute: "/pets/{id}", want4xx: 1},
+		{name: "unmatched", target: "/nowhere", wantRoute: "unmatched", want4xx: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := httpmiddleware.Metrics("Pets",
+				httpmiddleware.MetricsTo(&buf),
+				httpmiddleware.MetricsDimensions(metrics.Dimension{Name: "Service", Value: "pets"}),
+			)(mux)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, "pets", record["Service"])
+			assert.Equal(t, "GET", record["Method"])
+			assert.Equal(t, tt.wantRoute, record["Route"])
+			assert.InDelta(t, 1, record["Requests"], 0)
+			assert.InDelta(t, tt.want4xx, record["4xx"], 0)
+			assert.InDelta(t, 0, record["5xx"], 0)
+			assert.Contains(t, record, "Latency")
+			assert.Contains(t, record, "ResponseSize")
+		})
+	}
+}
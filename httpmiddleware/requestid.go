@@ -0,0 +1,140 @@
+package httpmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/lambdalog"
+	"github.com/geode-io/golambdas/tracing"
+)
+
+// incoming IDs longer than this, or with anything but printable ASCII, are
+// ignored rather than repeated into logs and headers
+const maxIncomingRequestIDLength = 128
+
+type correlationIDContextKey struct{}
+
+type requestIDOptions struct {
+	incomingHeaders []string
+	responseHeader  string
+}
+
+type RequestIDOption func(*requestIDOptions)
+
+// RequestIDFromHeaders replaces X-Request-Id and X-Correlation-Id as the
+// headers a caller's own ID is taken from, in order. None ignores callers'.
+func RequestIDFromHeaders(names ...string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.incomingHeaders = names
+	}
+}
+
+// RequestIDResponseHeader echoes the correlation ID in name instead of
+// X-Request-Id.
+func RequestIDResponseHeader(name string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.responseHeader = name
+	}
+}
+
+// RequestID picks one correlation ID per request: the caller's own if it
+// sent one, else the API Gateway request ID, the ALB trace ID or the Lambda
+// request ID, in that order. It is echoed in the response, available from
+// CorrelationIDFromContext, and logged with every record, along with the
+// gateway's IDs, by loggers with a lambdalog.Handler.
+func RequestID(opts ...RequestIDOption) func(http.Handler) http.Handler {
+	useOpts := requestIDOptions{
+		incomingHeaders: []string{"X-Request-Id", "X-Correlation-Id"},
+		responseHeader:  "X-Request-Id",
+	}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			var attrs []slog.Attr
+			var correlationID string
+			for _, name := range useOpts.incomingHeaders {
+				if id := r.Header.Get(name); validIncomingRequestID(id) {
+					correlationID = id
+					break
+				}
+			}
+
+			gatewayID, extendedID := gatewayRequestIDs(ctx)
+			if gatewayID != "" {
+				attrs = append(attrs, slog.String("gatewayRequestId", gatewayID))
+			}
+			if extendedID != "" {
+				attrs = append(attrs, slog.String("gatewayExtendedRequestId", extendedID))
+			}
+			for _, id := range []string{gatewayID, albTraceID(ctx), lambdaRequestID(ctx)} {
+				if correlationID == "" {
+					correlationID = id
+				}
+			}
+
+			if correlationID != "" {
+				attrs = append(attrs, slog.String("correlationId", correlationID))
+				ctx = context.WithValue(ctx, correlationIDContextKey{}, correlationID)
+				w.Header().Set(useOpts.responseHeader, correlationID)
+			}
+			next.ServeHTTP(w, r.WithContext(lambdalog.WithAttrs(ctx, attrs...)))
+		})
+	}
+}
+
+// CorrelationIDFromContext returns the ID RequestID picked for the request.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDContextKey{}).(string)
+	return id
+}
+
+func validIncomingRequestID(id string) bool {
+	if id == "" || len(id) > maxIncomingRequestIDLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func gatewayRequestIDs(ctx context.Context) (string, string) {
+	if event, ok := httpbridge.APIGatewayRequestFromContext(ctx); ok {
+		return event.RequestContext.RequestID, event.RequestContext.ExtendedRequestID
+	}
+	if event, ok := httpbridge.APIGatewayV2RequestFromContext(ctx); ok {
+		return event.RequestContext.RequestID, ""
+	}
+	return "", ""
+}
+
+// albTraceID returns the Root of the trace ID the load balancer added, such
+// as "1-5bdb40ca-556d8b0c50dc66f0511bf520".
+func albTraceID(ctx context.Context) string {
+	event, ok := httpbridge.ALBRequestFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	header := event.Headers["x-amzn-trace-id"]
+	if values := event.MultiValueHeaders["x-amzn-trace-id"]; len(values) > 0 {
+		header = values[0]
+	}
+	return tracing.ParseTraceHeader(header).Root
+}
+
+func lambdaRequestID(ctx context.Context) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package httpmiddleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/httpmiddleware"
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+func Test_RequestID(t *testing.T) {
+	const (
+		restEvent = `{"httpMethod":"GET","path":"/pets","headers":%s,
+			"requestContext":{"accountId":"123456789012","requestId":"rest-id","extendedRequestId":"ext-id"}}`
+		httpAPIEvent = `{"version":"2.0","rawPath":"/pets","headers":%s,
+			"requestContext":{"requestId":"http-id","http":{"method":"GET","path":"/pets"}}}`
+		albEvent = `{"httpMethod":"GET","path":"/pets","headers":%s,
+			"requestContext":{"elb":{"targetGroupArn":"arn:aws:elasticloadbalancing:us-east-1:1:targetgroup/p/1"}}}`
+	)
+	tests := []struct {
+		name      string
+		event     string
+		headers   string
+		opts      []httpmiddleware.RequestIDOption
+		want      string
+		wantAttrs map[string]any
+	}{
+		{
+			name:  "REST API",
+			event: restEvent, headers: `{}`,
+			want:      "rest-id",
+			wantAttrs: map[string]any{"gatewayRequestId": "rest-id", "gatewayExtendedRequestId": "ext-id"},
+		},
+		{
+			name:  "HTTP API",
+			event: httpAPIEvent, headers: `{}`,
+			want:      "http-id",
+			wantAttrs: map[string]any{"gatewayRequestId": "http-id"},
+		},
+		{
+			name:  "ALB",
+			event: albEvent, headers: `{"x-amzn-trace-id":"Root=1-5bdb40ca-556d8b0c50dc66f0511bf520"}`,
+			want: "1-5bdb40ca-556d8b0c50dc66f0511bf520",
+		},
+		{
+			name:  "ALB without trace ID",
+			event: albEvent, headers: `{}`,
+			want: "lambda-id",
+		},
+		{
+			name:  "incoming request ID",
+			event: restEvent, headers: `{"X-Request-Id":"client-id","X-Correlation-Id":"other-id"}`,
+			want:      "client-id",
+			wantAttrs: map[string]any{"gatewayRequestId": "rest-id"},
+		},
+		{
+			name:  "incoming correlation ID",
+			event: httpAPIEvent, headers: `{"x-correlation-id":"client-id"}`,
+			want: "client-id",
+		},
+		{
+			name:  "invalid incoming ID",
+			event: httpAPIEvent, headers: `{"x-request-id":"two words"}`,
+			want: "http-id",
+		},
+		{
+			name:  "incoming IDs ignored",
+			event: restEvent, headers: `{"X-Request-Id":"client-id"}`,
+			opts: []httpmiddleware.RequestIDOption{httpmiddleware.RequestIDFromHeaders()},
+			want: "rest-id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var got string
+			handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = httpmiddleware.CorrelationIDFromContext(r.Context())
+				lambdalog.FromContext(r.Context()).InfoContext(r.Context(), "handled")
+			})
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+				AwsRequestID: "lambda-id",
+			})
+			resp, err := httpbridge.ServeHTTP(handler,
+				httpbridge.HTTPMiddleware(httpmiddleware.RequestID(tt.opts...)),
+				httpbridge.Logger(slog.New(slog.NewJSONHandler(&buf, nil))),
+			).Invoke(ctx, []byte(fmt.Sprintf(tt.event, tt.headers)))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got)
+			var response struct {
+				Headers map[string]string `json:"headers"`
+			}
+			require.NoError(t, json.Unmarshal(resp, &response))
+			assert.Equal(t, tt.want, response.Headers["X-Request-Id"])
+
+			record := handledRecord(t, buf.Bytes())
+			assert.Equal(t, tt.want, record["correlationId"])
+			for key, want := range tt.wantAttrs {
+				assert.Equal(t, want, record[key], key)
+			}
+		})
+	}
+}
+
+func handledRecord(t *testing.T, logs []byte) map[string]any {
+	t.Helper()
+	for _, line := range bytes.Split(bytes.TrimSpace(logs), []byte("\n")) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(line, &record))
+		if record["msg"] == "handled" {
+			return record
+		}
+	}
+	t.Fatal("handler didn't log")
+	return nil
+}
@@ -0,0 +1,224 @@
+package httpmiddleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+type validateOptions struct {
+	authenticate      openapi3filter.AuthenticationFunc
+	validateResponses bool
+}
+
+type ValidateOption func(*validateOptions)
+
+// ValidateAuthentication checks the security requirements of operations with
+// authenticate, instead of leaving them to API Gateway authorizers.
+func ValidateAuthentication(authenticate openapi3filter.AuthenticationFunc) ValidateOption {
+	return func(o *validateOptions) {
+		o.authenticate = authenticate
+	}
+}
+
+// ValidateResponses also validates responses, and replaces those that don't
+// match the spec, including those with undocumented statuses, with a 500.
+// Responses are buffered to do so, so it is meant for development and test
+// stages, to catch drift between handlers and the spec before clients do.
+func ValidateResponses() ValidateOption {
+	return func(o *validateOptions) {
+		o.validateResponses = true
+	}
+}
+
+// Validate validates the parameters and JSON bodies of requests to the
+// operations in spec, such as the one oapi-codegen embeds for ServeAPI, and
+// answers those that are invalid with a 400 problem listing every error.
+// Requests spec has no operation for are served as they are, and bodies of
+// other media types, such as XML or images, aren't checked.
+//
+// Paths are matched without the spec's servers, as the bridge's BasePath and
+// StripBasePath leave them.
+func Validate(spec *openapi3.T, opts ...ValidateOption) (func(http.Handler) http.Handler, error) {
+	useOpts := validateOptions{authenticate: openapi3filter.NoopAuthenticationFunc}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+
+	withoutServers := *spec
+	withoutServers.Servers = nil
+	router, err := gorillamux.NewRouter(&withoutServers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to route spec operations: %w", err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					MultiError:         true,
+					AuthenticationFunc: useOpts.authenticate,
+					ExcludeRequestBody: !isJSONBody(r.Header),
+				},
+			}
+			if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+				writeValidationProblem(ctx, w, err)
+				return
+			}
+			if !useOpts.validateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buffered := &bufferedResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(buffered, r)
+			validateResponse(ctx, w, buffered, input)
+		})
+	}, nil
+}
+
+func validateResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	buffered *bufferedResponseWriter,
+	input *openapi3filter.RequestValidationInput,
+) {
+	status := buffered.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 w.Header(),
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			ExcludeResponseBody:   !isJSONBody(w.Header()),
+		},
+	}
+	responseInput.SetBodyBytes(buffered.body.Bytes())
+
+	if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+		lambdalog.FromContext(ctx).ErrorContext(ctx, "response does not match the OpenAPI spec",
+			"error", err, "request.method", input.Request.Method, "request.route", input.Route.Path,
+			"response.status", status)
+		for name := range w.Header() {
+			w.Header().Del(name)
+		}
+		httpbridge.WriteProblem(w, httpbridge.NewProblem(ctx, http.StatusInternalServerError, ""))
+		return
+	}
+	w.WriteHeader(status)
+	_, _ = io.Copy(w, &buffered.body)
+}
+
+// isJSONBody reports whether the body header describes is one to validate:
+// JSON, or without a valid Content-Type for kin-openapi to report. Others are
+// left alone, as kin-openapi fails media types it has no decoder for even when
+// the spec allows them.
+func isJSONBody(header http.Header) bool {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func writeValidationProblem(ctx context.Context, w http.ResponseWriter, err error) {
+	// MultiError matches errors.As if any of its errors does
+	var securityErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &securityErr) {
+		httpbridge.WriteProblem(w, httpbridge.NewProblem(ctx, http.StatusUnauthorized, ""))
+		return
+	}
+
+	problem := httpbridge.NewProblem(ctx, http.StatusBadRequest, "request does not match the API's specification")
+	for _, err := range unwrapMultiError(err) {
+		problem.Errors = append(problem.Errors, problemErrors(err)...)
+	}
+	httpbridge.WriteProblem(w, problem)
+}
+
+// problemErrors describes one validation error, as one entry per schema
+// violation within it.
+func problemErrors(err error) []httpbridge.ProblemError {
+	var base httpbridge.ProblemError
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.Parameter != nil {
+			base.Parameter = reqErr.Parameter.Name
+			base.In = reqErr.Parameter.In
+		}
+		if reqErr.Err == nil {
+			base.Detail = reqErr.Reason
+			return []httpbridge.ProblemError{base}
+		}
+		err = reqErr.Err
+	}
+
+	var problemErrs []httpbridge.ProblemError
+	for _, err := range unwrapMultiError(err) {
+		problemErr := base
+		problemErr.Detail = err.Error()
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			problemErr.Detail = schemaErr.Reason
+			if base.Parameter == "" {
+				problemErr.Pointer = jsonPointer(schemaErr.JSONPointer())
+			}
+		}
+		problemErrs = append(problemErrs, problemErr)
+	}
+	return problemErrs
+}
+
+// unwrapMultiError flattens err if it is a MultiError itself, leaving errors
+// that wrap one, such as a RequestError, whole.
+func unwrapMultiError(err error) []error {
+	multi, ok := err.(openapi3.MultiError) //nolint:errorlint // not what it wraps
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range multi {
+		errs = append(errs, unwrapMultiError(err)...)
+	}
+	return errs
+}
+
+// jsonPointer joins path as an RFC 6901 JSON pointer, which is "" for the
+// whole document.
+func jsonPointer(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
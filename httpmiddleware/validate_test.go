@@ -0,0 +1,191 @@
+package httpmiddleware_test
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/httpmiddleware"
+)
+
+const validatedSpec = `
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+servers: [{url: "https://api.example.com/v1"}]
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: integer, maximum: 100}}
+        - {name: X-Tenant, in: header, required: true, schema: {type: string}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: "#/components/schemas/Pet"}}
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+      responses:
+        "201": {description: created}
+  /pets/xml:
+    put:
+      requestBody:
+        required: true
+        content:
+          application/xml:
+            schema: {$ref: "#/components/schemas/Pet"}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/xml:
+              schema: {$ref: "#/components/schemas/Pet"}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, minLength: 1}
+        age: {type: integer, minimum: 0}
+`
+
+func Test_Validate(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(validatedSpec))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		opts       []httpmiddleware.ValidateOption
+		method     string
+		target     string
+		headers    map[string]string
+		body       string
+		response   string
+		mediaType  string
+		wantStatus int
+		wantErrors []httpbridge.ProblemError
+	}{
+		{
+			name:       "valid request",
+			method:     http.MethodGet,
+			target:     "/pets?limit=10",
+			headers:    map[string]string{"X-Tenant": "acme"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid parameters",
+			method:     http.MethodGet,
+			target:     "/pets?limit=1000",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []httpbridge.ProblemError{
+				{Detail: "number must be at most 100", Parameter: "limit", In: "query"},
+				{Detail: "value is required but missing", Parameter: "X-Tenant", In: "header"},
+			},
+		},
+		{
+			name:       "invalid body",
+			method:     http.MethodPost,
+			target:     "/pets",
+			headers:    map[string]string{"Content-Type": "application/json"},
+			body:       `{"name":"","age":-1}`,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []httpbridge.ProblemError{
+				{Detail: "number must be at least 0", Pointer: "/age"},
+				{Detail: "minimum string length is 1", Pointer: "/name"},
+			},
+		},
+		{
+			name:       "valid body",
+			method:     http.MethodPost,
+			target:     "/pets",
+			headers:    map[string]string{"Content-Type": "application/json"},
+			body:       `{"name":"rex"}`,
+			response:   `{}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "non-JSON bodies",
+			opts:       []httpmiddleware.ValidateOption{httpmiddleware.ValidateResponses()},
+			method:     http.MethodPut,
+			target:     "/pets/xml",
+			headers:    map[string]string{"Content-Type": "application/xml"},
+			body:       "<pet><name>rex</name></pet>",
+			response:   "<pet><name>rex</name></pet>",
+			mediaType:  "application/xml",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "operation not in spec",
+			method:     http.MethodDelete,
+			target:     "/pets",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid response",
+			opts:       []httpmiddleware.ValidateOption{httpmiddleware.ValidateResponses()},
+			method:     http.MethodGet,
+			target:     "/pets",
+			headers:    map[string]string{"X-Tenant": "acme"},
+			response:   `[{"name":"rex"}]`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "response drifted from spec",
+			opts:       []httpmiddleware.ValidateOption{httpmiddleware.ValidateResponses()},
+			method:     http.MethodGet,
+			target:     "/pets",
+			headers:    map[string]string{"X-Tenant": "acme"},
+			response:   `[{"age":3}]`,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validate, err := httpmiddleware.Validate(spec, tt.opts...)
+			require.NoError(t, err)
+			handler := validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := http.StatusOK
+				if r.Method == http.MethodPost {
+					body, _ := io.ReadAll(r.Body)
+					assert.Equal(t, tt.body, string(body))
+					status = http.StatusCreated
+				}
+				w.Header().Set("Content-Type", cmp.Or(tt.mediaType, "application/json"))
+				w.WriteHeader(status)
+				_, _ = io.WriteString(w, tt.response)
+			}))
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus < http.StatusBadRequest {
+				assert.Equal(t, tt.response, w.Body.String())
+				return
+			}
+			assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			var problem httpbridge.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.ElementsMatch(t, tt.wantErrors, problem.Errors)
+		})
+	}
+}
@@ -0,0 +1,340 @@
+// Package apigen generates the infrastructure that routes requests to a
+// function serving an OpenAPI spec through the httpbridge package, so API
+// Gateway routes and authorizers stay in sync with the spec the Go code is
+// generated from.
+package apigen
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Target is what invokes the function.
+type Target string
+
+const (
+	TargetRESTAPI     Target = "rest"
+	TargetHTTPAPI     Target = "http"
+	TargetFunctionURL Target = "url"
+	TargetALB         Target = "alb"
+)
+
+const (
+	integrationExtension     = "x-amazon-apigateway-integration"
+	authorizerExtension      = "x-amazon-apigateway-authorizer"
+	authTypeExtension        = "x-amazon-apigateway-authtype"
+	binaryMediaTypeExtension = "x-amazon-apigateway-binary-media-types"
+
+	// ALB rules take at most 5 condition values
+	albPathPatternsPerRule = 5
+)
+
+var ErrUnknownTarget = errors.New("unknown target")
+
+// Config is what Generate needs besides the spec. Functions are referred to
+// by their logical IDs in the template the fragment is merged into.
+type Config struct {
+	Target Target
+	// Function is the function serving the API.
+	Function string
+	// Authorizers maps security schemes of the spec to the Lambda authorizer
+	// functions that check them, for REST and HTTP APIs. Operations requiring
+	// other schemes are not authorized by API Gateway.
+	Authorizers map[string]string
+	// DefinitionLocation is where the template includes the generated OpenAPI
+	// document from, relative to it, for REST and HTTP APIs.
+	DefinitionLocation string
+	// StageName is the stage of REST APIs; HTTP APIs use $default.
+	StageName string
+	// URLAuthType is the AuthType of Function URLs, NONE or AWS_IAM.
+	URLAuthType string
+	// ListenerPriority is the priority of the first ALB listener rule, 1 if
+	// unset; the paths of the spec take as many consecutive ones as they need.
+	ListenerPriority int
+}
+
+// Generate returns the OpenAPI document to define a REST or HTTP API with,
+// which is nil for other targets, and the resources of a SAM template
+// fragment that invoke cfg.Function for the paths of spec.
+func Generate(spec *openapi3.T, cfg Config) (*openapi3.T, map[string]any, error) {
+	switch cfg.Target {
+	case TargetRESTAPI, TargetHTTPAPI:
+		for scheme := range cfg.Authorizers {
+			if spec.Components == nil || spec.Components.SecuritySchemes[scheme] == nil {
+				return nil, nil, fmt.Errorf("authorizer for unknown security scheme %q", scheme)
+			}
+		}
+		return definition(spec, cfg), apiTemplate(cfg), nil
+	case TargetFunctionURL:
+		return nil, functionURLTemplate(cfg), nil
+	case TargetALB:
+		if cfg.ListenerPriority == 0 {
+			cfg.ListenerPriority = 1
+		}
+		return nil, albTemplate(spec, cfg), nil
+	}
+	return nil, nil, fmt.Errorf("%w %q", ErrUnknownTarget, cfg.Target)
+}
+
+// definition copies spec with every operation integrated with cfg.Function
+// as a Lambda proxy. Servers are left out, as the bridge serves the paths of
+// the spec at the root of the API, or StripBasePath takes care of mappings.
+func definition(spec *openapi3.T, cfg Config) *openapi3.T {
+	doc := *spec
+	doc.Servers = nil
+	doc.Extensions = maps.Clone(spec.Extensions)
+	if doc.Extensions == nil {
+		doc.Extensions = map[string]any{}
+	}
+	if cfg.Target == TargetRESTAPI {
+		// REST APIs only return the bodies the bridge base64 encodes as binary
+		// for the media types listed, and decode request bodies for them too
+		doc.Extensions[binaryMediaTypeExtension] = []string{"*/*"}
+	}
+
+	integration := map[string]any{
+		"type":       "aws_proxy",
+		"httpMethod": "POST",
+		"uri":        sub(invocationsARN(cfg.Function)),
+	}
+	if cfg.Target == TargetRESTAPI {
+		integration["passthroughBehavior"] = "when_no_match"
+	} else {
+		integration["payloadFormatVersion"] = "2.0"
+	}
+
+	doc.Paths = openapi3.NewPaths()
+	for path, item := range spec.Paths.Map() {
+		integrated := *item
+		for method, op := range item.Operations() {
+			withIntegration := *op
+			withIntegration.Extensions = maps.Clone(op.Extensions)
+			if withIntegration.Extensions == nil {
+				withIntegration.Extensions = map[string]any{}
+			}
+			withIntegration.Extensions[integrationExtension] = integration
+			integrated.SetOperation(method, &withIntegration)
+		}
+		doc.Paths.Set(path, &integrated)
+	}
+
+	if len(cfg.Authorizers) > 0 {
+		components := *spec.Components
+		components.SecuritySchemes = maps.Clone(spec.Components.SecuritySchemes)
+		for name, authorizer := range cfg.Authorizers {
+			scheme := *components.SecuritySchemes[name].Value
+			scheme.Extensions = maps.Clone(scheme.Extensions)
+			if scheme.Extensions == nil {
+				scheme.Extensions = map[string]any{}
+			}
+			if cfg.Target == TargetRESTAPI {
+				scheme.Extensions[authTypeExtension] = "custom"
+			}
+			scheme.Extensions[authorizerExtension] = authorizerExtensionFor(&scheme, authorizer, cfg.Target)
+			// API Gateway only takes apiKey schemes for custom authorizers
+			scheme.Type = "apiKey"
+			scheme.Name = identityHeader(components.SecuritySchemes[name].Value)
+			scheme.In = "header"
+			scheme.Scheme, scheme.BearerFormat, scheme.Flows, scheme.OpenIdConnectUrl = "", "", nil, ""
+			components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{Value: &scheme}
+		}
+		doc.Components = &components
+	}
+	return &doc
+}
+
+func authorizerExtensionFor(scheme *openapi3.SecurityScheme, authorizer string, target Target) map[string]any {
+	header := identityHeader(scheme)
+	if target == TargetRESTAPI {
+		return map[string]any{
+			"type":                         "request",
+			"authorizerUri":                sub(invocationsARN(authorizer)),
+			"identitySource":               "method.request.header." + header,
+			"authorizerResultTtlInSeconds": 0,
+		}
+	}
+	return map[string]any{
+		"type":                           "request",
+		"authorizerUri":                  sub(invocationsARN(authorizer)),
+		"identitySource":                 "$request.header." + header,
+		"authorizerPayloadFormatVersion": "2.0",
+		"enableSimpleResponses":          true,
+	}
+}
+
+// identityHeader is the header a request must have for an authorizer to be
+// invoked, the one apiKey schemes in headers name, or Authorization.
+func identityHeader(scheme *openapi3.SecurityScheme) string {
+	if scheme.Type == "apiKey" && scheme.In == "header" && scheme.Name != "" {
+		return scheme.Name
+	}
+	return "Authorization"
+}
+
+func apiTemplate(cfg Config) map[string]any {
+	api := cfg.Function + "Api"
+	properties := map[string]any{
+		"DefinitionBody": map[string]any{
+			"Fn::Transform": map[string]any{
+				"Name":       "AWS::Include",
+				"Parameters": map[string]any{"Location": cfg.DefinitionLocation},
+			},
+		},
+	}
+	apiType := "AWS::Serverless::HttpApi"
+	apiURL := "https://${" + api + "}.execute-api.${AWS::Region}.${AWS::URLSuffix}/"
+	if cfg.Target == TargetRESTAPI {
+		apiType = "AWS::Serverless::Api"
+		properties["StageName"] = cfg.StageName
+		apiURL += cfg.StageName + "/"
+	}
+
+	resources := map[string]any{
+		api: map[string]any{"Type": apiType, "Properties": properties},
+	}
+	sourceARN := sub("arn:${AWS::Partition}:execute-api:${AWS::Region}:${AWS::AccountId}:${" + api + "}/*")
+	resources[cfg.Function+"ApiPermission"] = permission(cfg.Function, "apigateway.amazonaws.com", sourceARN)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Authorizers)) {
+		authorizer := cfg.Authorizers[name]
+		resources[authorizer+"ApiPermission"] = permission(authorizer, "apigateway.amazonaws.com", sourceARN)
+	}
+
+	return map[string]any{
+		"Resources": resources,
+		"Outputs": map[string]any{
+			api + "URL": map[string]any{"Value": sub(apiURL)},
+		},
+	}
+}
+
+func functionURLTemplate(cfg Config) map[string]any {
+	authType := cfg.URLAuthType
+	if authType == "" {
+		authType = "NONE"
+	}
+	url := cfg.Function + "URL"
+	resources := map[string]any{
+		url: map[string]any{
+			"Type": "AWS::Lambda::Url",
+			"Properties": map[string]any{
+				"TargetFunctionArn": getAtt(cfg.Function, "Arn"),
+				"AuthType":          authType,
+			},
+		},
+	}
+	if authType == "NONE" {
+		resources[url+"Permission"] = map[string]any{
+			"Type": "AWS::Lambda::Permission",
+			"Properties": map[string]any{
+				"Action":              "lambda:InvokeFunctionUrl",
+				"FunctionName":        ref(cfg.Function),
+				"Principal":           "*",
+				"FunctionUrlAuthType": "NONE",
+			},
+		}
+	}
+	return map[string]any{
+		"Resources": resources,
+		"Outputs": map[string]any{
+			url: map[string]any{"Value": getAtt(url, "FunctionUrl")},
+		},
+	}
+}
+
+func albTemplate(spec *openapi3.T, cfg Config) map[string]any {
+	targetGroup := cfg.Function + "TargetGroup"
+	permissionID := cfg.Function + "TargetGroupPermission"
+	resources := map[string]any{
+		permissionID: permission(cfg.Function, "elasticloadbalancing.amazonaws.com", nil),
+		targetGroup: map[string]any{
+			"Type":      "AWS::ElasticLoadBalancingV2::TargetGroup",
+			"DependsOn": permissionID,
+			"Properties": map[string]any{
+				"TargetType": "lambda",
+				"Targets":    []any{map[string]any{"Id": getAtt(cfg.Function, "Arn")}},
+			},
+		},
+	}
+
+	i := 0
+	for chunk := range slices.Chunk(pathPatterns(spec), albPathPatternsPerRule) {
+		resources[fmt.Sprintf("%sListenerRule%d", cfg.Function, i+1)] = map[string]any{
+			"Type": "AWS::ElasticLoadBalancingV2::ListenerRule",
+			"Properties": map[string]any{
+				"ListenerArn": ref("ListenerArn"),
+				"Priority":    cfg.ListenerPriority + i,
+				"Conditions": []any{map[string]any{
+					"Field":             "path-pattern",
+					"PathPatternConfig": map[string]any{"Values": chunk},
+				}},
+				"Actions": []any{map[string]any{
+					"Type":           "forward",
+					"TargetGroupArn": ref(targetGroup),
+				}},
+			},
+		}
+		i++
+	}
+
+	return map[string]any{
+		"Parameters": map[string]any{
+			"ListenerArn": map[string]any{
+				"Type":        "String",
+				"Description": "ARN of the listener to route the API's paths to the function from",
+			},
+		},
+		"Resources": resources,
+	}
+}
+
+// pathPatterns returns the sorted ALB path patterns matching the paths of
+// spec, with each path parameter matching anything. Every rule forwards to
+// the function, so their order doesn't matter.
+func pathPatterns(spec *openapi3.T) []string {
+	var patterns []string
+	for path := range spec.Paths.Map() {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if strings.Contains(segment, "{") {
+				segments[i] = "*"
+			}
+		}
+		patterns = append(patterns, strings.Join(segments, "/"))
+	}
+	slices.Sort(patterns)
+	return slices.Compact(patterns)
+}
+
+func permission(function string, principal string, sourceARN any) map[string]any {
+	properties := map[string]any{
+		"Action":       "lambda:InvokeFunction",
+		"FunctionName": ref(function),
+		"Principal":    principal,
+	}
+	if sourceARN != nil {
+		properties["SourceArn"] = sourceARN
+	}
+	return map[string]any{"Type": "AWS::Lambda::Permission", "Properties": properties}
+}
+
+func invocationsARN(function string) string {
+	return "arn:${AWS::Partition}:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/${" +
+		function + ".Arn}/invocations"
+}
+
+func sub(s string) map[string]any {
+	return map[string]any{"Fn::Sub": s}
+}
+
+func ref(logicalID string) map[string]any {
+	return map[string]any{"Ref": logicalID}
+}
+
+func getAtt(logicalID string, attribute string) map[string]any {
+	return map[string]any{"Fn::GetAtt": []string{logicalID, attribute}}
+}
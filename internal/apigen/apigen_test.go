@@ -0,0 +1,218 @@
+package apigen_test
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/internal/apigen"
+)
+
+const petsSpec = `
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+servers: [{url: "https://api.example.com/v1"}]
+components:
+  securitySchemes:
+    bearerAuth: {type: http, scheme: bearer}
+    apiKey: {type: apiKey, in: header, name: X-Api-Key}
+paths:
+  /pets:
+    get: {responses: {"200": {description: ok}}}
+    post: {responses: {"201": {description: created}}}
+  /pets/{id}:
+    parameters: [{name: id, in: path, required: true, schema: {type: string}}]
+    get:
+      security: [{bearerAuth: []}]
+      responses: {"200": {description: ok}}
+  /pets/{id}/photos/{photo}.jpg:
+    get: {responses: {"200": {description: ok}}}
+  /a: {get: {responses: {"200": {description: ok}}}}
+  /b: {get: {responses: {"200": {description: ok}}}}
+  /c: {get: {responses: {"200": {description: ok}}}}
+`
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(petsSpec))
+	require.NoError(t, err)
+	return spec
+}
+
+func Test_Generate_APIs(t *testing.T) {
+	invocations := invocationsARN("ApiFunction")
+	tests := []struct {
+		name            string
+		target          apigen.Target
+		wantIntegration map[string]any
+		wantAuthorizer  map[string]any
+		wantAPIType     string
+	}{
+		{
+			name:   "REST API",
+			target: apigen.TargetRESTAPI,
+			wantIntegration: map[string]any{
+				"type":                "aws_proxy",
+				"httpMethod":          "POST",
+				"uri":                 map[string]any{"Fn::Sub": invocations},
+				"passthroughBehavior": "when_no_match",
+			},
+			wantAuthorizer: map[string]any{
+				"type":                         "request",
+				"authorizerUri":                map[string]any{"Fn::Sub": invocationsARN("Auth")},
+				"identitySource":               "method.request.header.Authorization",
+				"authorizerResultTtlInSeconds": 0,
+			},
+			wantAPIType: "AWS::Serverless::Api",
+		},
+		{
+			name:   "HTTP API",
+			target: apigen.TargetHTTPAPI,
+			wantIntegration: map[string]any{
+				"type":                 "aws_proxy",
+				"httpMethod":           "POST",
+				"uri":                  map[string]any{"Fn::Sub": invocations},
+				"payloadFormatVersion": "2.0",
+			},
+			wantAuthorizer: map[string]any{
+				"type":                           "request",
+				"authorizerUri":                  map[string]any{"Fn::Sub": invocationsARN("Auth")},
+				"identitySource":                 "$request.header.Authorization",
+				"authorizerPayloadFormatVersion": "2.0",
+				"enableSimpleResponses":          true,
+			},
+			wantAPIType: "AWS::Serverless::HttpApi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := loadSpec(t)
+			definition, template, err := apigen.Generate(spec, apigen.Config{
+				Target:             tt.target,
+				Function:           "ApiFunction",
+				Authorizers:        map[string]string{"bearerAuth": "Auth"},
+				DefinitionLocation: "openapi.aws.yaml",
+				StageName:          "prod",
+			})
+			require.NoError(t, err)
+
+			assert.Empty(t, definition.Servers)
+			for path, item := range definition.Paths.Map() {
+				for method, op := range item.Operations() {
+					assert.Equal(t, tt.wantIntegration, op.Extensions["x-amazon-apigateway-integration"], method+" "+path)
+				}
+			}
+			scheme := definition.Components.SecuritySchemes["bearerAuth"].Value
+			assert.Equal(t, "apiKey", scheme.Type)
+			assert.Equal(t, "Authorization", scheme.Name)
+			assert.Equal(t, tt.wantAuthorizer, scheme.Extensions["x-amazon-apigateway-authorizer"])
+			assert.Nil(t, definition.Components.SecuritySchemes["apiKey"].Value.Extensions["x-amazon-apigateway-authorizer"])
+
+			// the spec itself is left as it was
+			assert.Len(t, spec.Servers, 1)
+			assert.Equal(t, "http", spec.Components.SecuritySchemes["bearerAuth"].Value.Type)
+			assert.Empty(t, spec.Paths.Value("/pets").Get.Extensions)
+
+			resources, ok := template["Resources"].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantAPIType, lookup(t, resources, "ApiFunctionApi", "Type"))
+			assert.Equal(t, "openapi.aws.yaml", lookup(t, resources, "ApiFunctionApi", "Properties", "DefinitionBody",
+				"Fn::Transform", "Parameters", "Location"))
+			assert.Equal(t, map[string]any{"Ref": "Auth"},
+				lookup(t, resources, "AuthApiPermission", "Properties", "FunctionName"))
+		})
+	}
+}
+
+func Test_Generate_ALB(t *testing.T) {
+	_, template, err := apigen.Generate(loadSpec(t), apigen.Config{
+		Target:           apigen.TargetALB,
+		Function:         "ApiFunction",
+		ListenerPriority: 10,
+	})
+	require.NoError(t, err)
+
+	resources, ok := template["Resources"].(map[string]any)
+	require.True(t, ok)
+	rule := func(name string) []string {
+		conditions, ok := lookup(t, resources, name, "Properties", "Conditions").([]any)
+		require.True(t, ok)
+		values, ok := lookup(t, conditions[0], "PathPatternConfig", "Values").([]string)
+		require.True(t, ok)
+		return values
+	}
+	assert.Equal(t, []string{"/a", "/b", "/c", "/pets", "/pets/*"}, rule("ApiFunctionListenerRule1"))
+	assert.Equal(t, 10, lookup(t, resources, "ApiFunctionListenerRule1", "Properties", "Priority"))
+	assert.Equal(t, []string{"/pets/*/photos/*"}, rule("ApiFunctionListenerRule2"))
+	assert.Equal(t, 11, lookup(t, resources, "ApiFunctionListenerRule2", "Properties", "Priority"))
+	assert.Equal(t, "lambda", lookup(t, resources, "ApiFunctionTargetGroup", "Properties", "TargetType"))
+}
+
+func Test_Generate_ALBDefaultPriority(t *testing.T) {
+	_, template, err := apigen.Generate(loadSpec(t), apigen.Config{
+		Target:   apigen.TargetALB,
+		Function: "ApiFunction",
+	})
+	require.NoError(t, err)
+
+	resources, ok := template["Resources"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, 1, lookup(t, resources, "ApiFunctionListenerRule1", "Properties", "Priority"))
+	assert.Equal(t, 2, lookup(t, resources, "ApiFunctionListenerRule2", "Properties", "Priority"))
+}
+
+func Test_Generate_FunctionURL(t *testing.T) {
+	tests := []struct {
+		authType       string
+		wantPermission bool
+	}{
+		{authType: "", wantPermission: true},
+		{authType: "AWS_IAM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.authType, func(t *testing.T) {
+			definition, template, err := apigen.Generate(loadSpec(t), apigen.Config{
+				Target:      apigen.TargetFunctionURL,
+				Function:    "ApiFunction",
+				URLAuthType: tt.authType,
+			})
+			require.NoError(t, err)
+			assert.Nil(t, definition)
+
+			resources, ok := template["Resources"].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, "AWS::Lambda::Url", lookup(t, resources, "ApiFunctionURL", "Type"))
+			assert.Equal(t, tt.wantPermission, resources["ApiFunctionURLPermission"] != nil)
+		})
+	}
+}
+
+func Test_Generate_Errors(t *testing.T) {
+	_, _, err := apigen.Generate(loadSpec(t), apigen.Config{Target: "lambda"})
+	require.ErrorIs(t, err, apigen.ErrUnknownTarget)
+
+	_, _, err = apigen.Generate(loadSpec(t), apigen.Config{
+		Target:      apigen.TargetHTTPAPI,
+		Authorizers: map[string]string{"oauth": "Auth"},
+	})
+	require.ErrorContains(t, err, `"oauth"`)
+}
+
+// invocationsARN is the invocations ARN of function.
+func invocationsARN(function string) string {
+	return "arn:${AWS::Partition}:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/${" +
+		function + ".Arn}/invocations"
+}
+
+func lookup(t *testing.T, v any, keys ...string) any {
+	t.Helper()
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		require.True(t, ok, key)
+		v = m[key]
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package lambdalog
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerContextKey struct{}
+
+type attrsContextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, for FromContext.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, which the HTTP
+// bridge does for every request. Without one, it is Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return Default()
+}
+
+// WithAttrs returns a copy of ctx whose attributes a Handler adds to every
+// record logged with it, on top of those already there.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := attrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsContextKey{}, merged)
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsContextKey{}).([]slog.Attr)
+	return attrs
+}
@@ -0,0 +1,109 @@
+package lambdalog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/geode-io/golambdas/lambdamiddleware"
+	"github.com/geode-io/golambdas/tracing"
+)
+
+// Handler adds the invocation's metadata to every record: the Lambda request
+// ID, function name and version, whether it's a cold start, the X-Ray trace ID
+// and any attributes added with WithAttrs. They stay at the top level of
+// records logged through WithGroup, where Lambda's JSON log format has them.
+type Handler struct {
+	// next has the attributes added before any group was opened
+	next   slog.Handler
+	groups []group
+}
+
+// group is one opened with WithGroup, with the attributes added after it.
+type group struct {
+	name  string
+	attrs []slog.Attr
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler wraps next, unless it already is a *Handler.
+func NewHandler(next slog.Handler) *Handler {
+	if h, ok := next.(*Handler); ok {
+		return h
+	}
+	return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if len(h.groups) == 0 {
+		record = record.Clone()
+		record.AddAttrs(invocationAttrs(ctx)...)
+		return h.next.Handle(ctx, record)
+	}
+
+	// next knows nothing of the groups, so the record's attributes are nested
+	// in them here, leaving the invocation's at the top level
+	var attrs []slog.Attr
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		group := h.groups[i]
+		attrs = []slog.Attr{{
+			Key:   group.name,
+			Value: slog.GroupValue(append(slices.Clip(group.attrs), attrs...)...),
+		}}
+	}
+	nested := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	nested.AddAttrs(attrs...)
+	nested.AddAttrs(invocationAttrs(ctx)...)
+	return h.next.Handle(ctx, nested)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	if len(h.groups) == 0 {
+		return &Handler{next: h.next.WithAttrs(attrs)}
+	}
+	groups := slices.Clone(h.groups)
+	last := &groups[len(groups)-1]
+	last.attrs = append(slices.Clip(last.attrs), attrs...)
+	return &Handler{next: h.next, groups: groups}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{next: h.next, groups: append(slices.Clip(h.groups), group{name: name})}
+}
+
+func invocationAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		attrs = append(attrs,
+			slog.String("requestId", lc.AwsRequestID),
+			slog.Bool("coldStart", lambdamiddleware.IsColdStart(ctx)),
+		)
+	}
+	if lambdacontext.FunctionName != "" {
+		attrs = append(attrs,
+			slog.String("functionName", lambdacontext.FunctionName),
+			slog.String("functionVersion", lambdacontext.FunctionVersion),
+		)
+	}
+	if traceID := tracing.InvocationHeader(ctx).Root; traceID != "" {
+		attrs = append(attrs, slog.String("xrayTraceId", traceID))
+	}
+	return append(attrs, attrsFromContext(ctx)...)
+}
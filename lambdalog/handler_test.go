@@ -0,0 +1,157 @@
+package lambdalog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+func Test_New(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		level     string
+		log       func(ctx context.Context, logger *slog.Logger)
+		wantLines int
+	}{
+		{
+			name:      "defaults to INFO",
+			format:    "JSON",
+			log:       func(ctx context.Context, logger *slog.Logger) { logger.DebugContext(ctx, "hidden") },
+			wantLines: 0,
+		},
+		{
+			name:   "TRACE enables everything",
+			format: "JSON",
+			level:  "TRACE",
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.Log(ctx, lambdalog.LevelTrace, "shown")
+			},
+			wantLines: 1,
+		},
+		{
+			name:   "ERROR hides warnings",
+			format: "JSON",
+			level:  "error",
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.WarnContext(ctx, "hidden")
+				logger.ErrorContext(ctx, "shown")
+			},
+			wantLines: 1,
+		},
+		{
+			name:      "text",
+			format:    "Text",
+			log:       func(ctx context.Context, logger *slog.Logger) { logger.InfoContext(ctx, "shown") },
+			wantLines: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_LAMBDA_LOG_FORMAT", tt.format)
+			t.Setenv("AWS_LAMBDA_LOG_LEVEL", tt.level)
+			var buf bytes.Buffer
+			tt.log(context.Background(), lambdalog.New(&buf))
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if buf.Len() == 0 {
+				lines = nil
+			}
+			require.Len(t, lines, tt.wantLines)
+			for _, line := range lines {
+				assert.Contains(t, line, "shown")
+			}
+		})
+	}
+}
+
+func Test_New_JSON(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_LOG_FORMAT", "JSON")
+	t.Setenv("AWS_LAMBDA_LOG_LEVEL", "")
+	t.Setenv("_X_AMZN_TRACE_ID", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID: "6f4b2c1e-8a64-4a35-9c0e-2b3fb2b5e9d1",
+	})
+	ctx = lambdalog.WithAttrs(ctx, slog.String("tenant", "acme"))
+
+	var buf bytes.Buffer
+	lambdalog.New(&buf).Log(ctx, lambdalog.LevelFatal, "giving up", "reason", "disk full")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z$`, line["timestamp"])
+	delete(line, "timestamp")
+	delete(line, "coldStart")
+	assert.Equal(t, map[string]any{
+		"level":       "FATAL",
+		"message":     "giving up",
+		"reason":      "disk full",
+		"requestId":   "6f4b2c1e-8a64-4a35-9c0e-2b3fb2b5e9d1",
+		"xrayTraceId": "1-5759e988-bd862e3fe1be46a994272793",
+		"tenant":      "acme",
+	}, line)
+}
+
+func Test_Handler_WithGroup(t *testing.T) {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID: "6f4b2c1e-8a64-4a35-9c0e-2b3fb2b5e9d1",
+	})
+	var buf bytes.Buffer
+	logger := slog.New(lambdalog.NewHandler(slog.NewJSONHandler(&buf, nil))).
+		With("service", "pets").
+		WithGroup("http").
+		With("method", "GET").
+		WithGroup("response")
+	logger.InfoContext(ctx, "handled", "status", 200)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "pets", line["service"])
+	assert.Equal(t, "6f4b2c1e-8a64-4a35-9c0e-2b3fb2b5e9d1", line["requestId"])
+	assert.Contains(t, line, "coldStart")
+	assert.Equal(t, map[string]any{
+		"method":   "GET",
+		"response": map[string]any{"status": float64(200)},
+	}, line["http"])
+}
+
+func Test_FromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	assert.Same(t, logger, lambdalog.FromContext(lambdalog.NewContext(context.Background(), logger)))
+	assert.IsType(t, &lambdalog.Handler{}, lambdalog.FromContext(context.Background()).Handler())
+
+	wrapped := lambdalog.NewHandler(logger.Handler())
+	assert.Same(t, wrapped, lambdalog.NewHandler(wrapped))
+}
+
+func Test_Default_SetDefault(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_LOG_LEVEL", "WARN")
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(previous)
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID: "6f4b2c1e-8a64-4a35-9c0e-2b3fb2b5e9d1",
+	})
+	logger := lambdalog.Default()
+	logger.InfoContext(ctx, "hidden")
+	logger.WarnContext(ctx, "shown")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line), buf.String())
+	assert.Equal(t, "shown", line["msg"])
+	assert.Equal(t, "6f4b2c1e-8a64-4a35-9c0e-2b3fb2b5e9d1", line["requestId"])
+}
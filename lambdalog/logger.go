@@ -0,0 +1,119 @@
+package lambdalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Lambda's log levels beyond the ones slog has.
+const (
+	LevelTrace = slog.LevelDebug - 4
+	LevelFatal = slog.LevelError + 4
+)
+
+const (
+	logLevelEnv  = "AWS_LAMBDA_LOG_LEVEL"
+	logFormatEnv = "AWS_LAMBDA_LOG_FORMAT"
+)
+
+// timestamps as the Lambda runtime writes them in JSON logs
+const timestampLayout = "2006-01-02T15:04:05.000Z"
+
+var levelNames = map[slog.Level]string{
+	LevelTrace: "TRACE",
+	LevelFatal: "FATAL",
+}
+
+// New returns a logger writing to w the way the function's logging
+// configuration asks: JSON shaped like the runtime's own log lines when
+// AWS_LAMBDA_LOG_FORMAT is JSON and text otherwise, at AWS_LAMBDA_LOG_LEVEL
+// or INFO. Records carry the invocation's metadata, see Handler.
+func New(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: levelFromEnv()}
+	if strings.EqualFold(os.Getenv(logFormatEnv), "JSON") {
+		opts.ReplaceAttr = replaceJSONAttr
+		return slog.New(NewHandler(slog.NewJSONHandler(w, opts)))
+	}
+	opts.ReplaceAttr = replaceLevelName
+	return slog.New(NewHandler(slog.NewTextHandler(w, opts)))
+}
+
+// the handler slog.Default() has until slog.SetDefault replaces it
+var builtinHandler = slog.Default().Handler()
+
+// Default is New writing to stdout, where the runtime picks logs up, unless
+// slog.SetDefault installed a logger of its own. Records then go through that
+// logger's handler, in its format, with the invocation's metadata and at
+// AWS_LAMBDA_LOG_LEVEL when it is set.
+func Default() *slog.Logger {
+	handler := slog.Default().Handler()
+	if handler == builtinHandler {
+		return New(os.Stdout)
+	}
+	if _, ok := handler.(*Handler); !ok && os.Getenv(logLevelEnv) != "" {
+		handler = &levelHandler{Handler: handler, level: levelFromEnv()}
+	}
+	return slog.New(NewHandler(handler))
+}
+
+// levelHandler drops records below level before they reach the Handler.
+type levelHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+func levelFromEnv() slog.Level {
+	name := strings.ToUpper(strings.TrimSpace(os.Getenv(logLevelEnv)))
+	for level, levelName := range levelNames {
+		if name == levelName {
+			return level
+		}
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+func replaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || a.Key != slog.LevelKey {
+		return a
+	}
+	if level, ok := a.Value.Any().(slog.Level); ok {
+		if name, ok := levelNames[level]; ok {
+			a.Value = slog.StringValue(name)
+		}
+	}
+	return a
+}
+
+func replaceJSONAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		return slog.String("timestamp", a.Value.Time().UTC().Format(timestampLayout))
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		return replaceLevelName(groups, a)
+	}
+	return a
+}
@@ -0,0 +1,230 @@
+package lambdalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// headers that are redacted whatever Payloads says
+var defaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Amz-Security-Token",
+}
+
+// Payloads controls how request and response payloads are logged. The zero
+// value logs every payload in full at INFO, minus credential headers.
+type Payloads struct {
+	// Disabled stops payloads being logged at all.
+	Disabled bool
+	// Level is the level payloads are logged at.
+	Level slog.Level
+	// SampleRate is the fraction of invocations, between 0 and 1, whose
+	// payloads are logged. 0 logs them all.
+	SampleRate float64
+	// MaxBodySize truncates bodies longer than this many bytes. 0 doesn't.
+	MaxBodySize int
+	// RedactHeaders are redacted on top of Authorization, Cookie and the
+	// other headers that always are. Names are case-insensitive.
+	RedactHeaders []string
+	// RedactQueryParams are redacted wherever they appear in the query string.
+	RedactQueryParams []string
+	// RedactJSONFields are redacted at any depth of JSON bodies.
+	RedactJSONFields []string
+}
+
+type payloadsContextKey struct{}
+
+// WithPayloads returns a copy of ctx carrying payloads, for
+// PayloadsFromContext. Sampling is decided here, once per invocation, so
+// everything logging payloads for it agrees.
+func WithPayloads(ctx context.Context, payloads Payloads) context.Context {
+	if payloads.SampleRate > 0 && rand.Float64() >= payloads.SampleRate { //nolint:gosec // sampling, not security
+		payloads.Disabled = true
+	}
+	return context.WithValue(ctx, payloadsContextKey{}, payloads)
+}
+
+// PayloadsFromContext returns the Payloads stored by WithPayloads, which is
+// Disabled when the invocation wasn't sampled.
+func PayloadsFromContext(ctx context.Context) Payloads {
+	payloads, _ := ctx.Value(payloadsContextKey{}).(Payloads)
+	return payloads
+}
+
+// Log logs payload, a Lambda HTTP event or response, as attribute key unless
+// payloads are disabled.
+func (p Payloads) Log(ctx context.Context, logger *slog.Logger, msg string, key string, payload []byte) {
+	if p.Disabled || !logger.Enabled(ctx, p.Level) {
+		return
+	}
+	logger.Log(ctx, p.Level, msg, key, p.Event(payload))
+}
+
+// Event returns a redacted copy of payload, a Lambda HTTP event or response,
+// with its body truncated.
+func (p Payloads) Event(payload []byte) json.RawMessage {
+	var event map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		quoted, _ := json.Marshal(p.truncate(string(payload)))
+		return quoted
+	}
+
+	for _, key := range []string{"headers", "multiValueHeaders"} {
+		if headers, ok := event[key].(map[string]any); ok {
+			for name := range headers {
+				if p.redactsHeader(name) {
+					headers[name] = redactedValue(headers[name])
+				}
+			}
+		}
+	}
+	// HTTP API events and responses carry cookies outside the headers
+	cookieHeader := "Cookie"
+	if _, ok := event["statusCode"]; ok {
+		cookieHeader = "Set-Cookie"
+	}
+	if _, ok := event["cookies"]; ok && p.redactsHeader(cookieHeader) {
+		event["cookies"] = []string{redacted}
+	}
+
+	for _, key := range []string{"queryStringParameters", "multiValueQueryStringParameters"} {
+		if params, ok := event[key].(map[string]any); ok {
+			for name := range params {
+				if slices.Contains(p.RedactQueryParams, name) {
+					params[name] = redactedValue(params[name])
+				}
+			}
+		}
+	}
+	if rawQuery, ok := event["rawQueryString"].(string); ok {
+		event["rawQueryString"] = p.RawQuery(rawQuery)
+	}
+
+	if body, ok := event["body"].(string); ok {
+		if isBase64, _ := event["isBase64Encoded"].(bool); !isBase64 {
+			body = p.jsonBody(body)
+		}
+		event["body"] = p.truncate(body)
+	}
+
+	redactedEvent, err := json.Marshal(event)
+	if err != nil {
+		return json.RawMessage(`null`)
+	}
+	return redactedEvent
+}
+
+// Header returns a redacted copy of header.
+func (p Payloads) Header(header http.Header) http.Header {
+	out := header.Clone()
+	for name := range out {
+		if p.redactsHeader(name) {
+			out[name] = []string{redacted}
+		}
+	}
+	return out
+}
+
+// Query returns a redacted copy of query.
+func (p Payloads) Query(query url.Values) url.Values {
+	out := make(url.Values, len(query))
+	for name, values := range query {
+		if slices.Contains(p.RedactQueryParams, name) {
+			values = []string{redacted}
+		}
+		out[name] = values
+	}
+	return out
+}
+
+// Body returns body redacted, if it is JSON, and truncated.
+func (p Payloads) Body(body []byte) string {
+	return p.truncate(p.jsonBody(string(body)))
+}
+
+func (p Payloads) redactsHeader(name string) bool {
+	matches := func(redactedName string) bool {
+		return strings.EqualFold(name, redactedName)
+	}
+	return slices.ContainsFunc(defaultRedactedHeaders, matches) || slices.ContainsFunc(p.RedactHeaders, matches)
+}
+
+// RawQuery returns a redacted copy of rawQuery, in its order and encoding.
+func (p Payloads) RawQuery(rawQuery string) string {
+	if len(p.RedactQueryParams) == 0 {
+		return rawQuery
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && slices.Contains(p.RedactQueryParams, name) {
+			pairs[i] = key + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func (p Payloads) jsonBody(body string) string {
+	if len(p.RedactJSONFields) == 0 || !json.Valid([]byte(body)) {
+		return body
+	}
+	var doc any
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+	redactedBody, err := json.Marshal(p.redactFields(doc))
+	if err != nil {
+		return body
+	}
+	return string(redactedBody)
+}
+
+func (p Payloads) redactFields(doc any) any {
+	switch v := doc.(type) {
+	case map[string]any:
+		for name, value := range v {
+			if slices.Contains(p.RedactJSONFields, name) {
+				v[name] = redacted
+			} else {
+				v[name] = p.redactFields(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = p.redactFields(value)
+		}
+	}
+	return doc
+}
+
+func (p Payloads) truncate(body string) string {
+	if p.MaxBodySize <= 0 || len(body) <= p.MaxBodySize {
+		return body
+	}
+	return fmt.Sprintf("%s...(%d more bytes)", body[:p.MaxBodySize], len(body)-p.MaxBodySize)
+}
+
+func redactedValue(value any) any {
+	if _, ok := value.([]any); ok {
+		return []string{redacted}
+	}
+	return redacted
+}
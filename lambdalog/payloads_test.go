@@ -0,0 +1,113 @@
+package lambdalog_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+func Test_Payloads_Event(t *testing.T) {
+	payloads := lambdalog.Payloads{
+		MaxBodySize:       50,
+		RedactHeaders:     []string{"x-session"},
+		RedactQueryParams: []string{"token"},
+		RedactJSONFields:  []string{"password"},
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{
+			name: "REST API event",
+			payload: `{
+				"httpMethod": "POST",
+				"headers": {"authorization": "Bearer abc", "X-Session": "s", "accept": "*/*"},
+				"multiValueHeaders": {"Authorization": ["Bearer abc"]},
+				"queryStringParameters": {"token": "t", "page": "2"},
+				"multiValueQueryStringParameters": {"token": ["t"], "page": ["2"]},
+				"body": "{\"user\":\"ann\",\"password\":\"hunter2\",\"n\":1}",
+				"isBase64Encoded": false
+			}`,
+			want: `{
+				"httpMethod": "POST",
+				"headers": {"authorization": "[REDACTED]", "X-Session": "[REDACTED]", "accept": "*/*"},
+				"multiValueHeaders": {"Authorization": ["[REDACTED]"]},
+				"queryStringParameters": {"token": "[REDACTED]", "page": "2"},
+				"multiValueQueryStringParameters": {"token": ["[REDACTED]"], "page": ["2"]},
+				"body": "{\"n\":1,\"password\":\"[REDACTED]\",\"user\":\"ann\"}",
+				"isBase64Encoded": false
+			}`,
+		},
+		{
+			name: "HTTP API event",
+			payload: `{
+				"rawQueryString": "page=2&token=t&token=u",
+				"cookies": ["a=b"],
+				"body": "aGVsbG8=",
+				"isBase64Encoded": true
+			}`,
+			want: `{
+				"rawQueryString": "page=2&token=%5BREDACTED%5D&token=%5BREDACTED%5D",
+				"cookies": ["[REDACTED]"],
+				"body": "aGVsbG8=",
+				"isBase64Encoded": true
+			}`,
+		},
+		{
+			name: "response",
+			payload: `{
+				"statusCode": 200,
+				"headers": {"Set-Cookie": "a=b"},
+				"body": "01234567890123456789012345678901234567890123456789more"
+			}`,
+			want: `{
+				"statusCode": 200,
+				"headers": {"Set-Cookie": "[REDACTED]"},
+				"body": "01234567890123456789012345678901234567890123456789...(4 more bytes)"
+			}`,
+		},
+		{
+			name:    "not an event",
+			payload: `nope`,
+			want:    `"nope"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, string(payloads.Event([]byte(tt.payload))))
+		})
+	}
+}
+
+func Test_Payloads_HTTP(t *testing.T) {
+	payloads := lambdalog.Payloads{RedactQueryParams: []string{"token"}, MaxBodySize: 4}
+
+	assert.Equal(t, http.Header{
+		"Authorization": {"[REDACTED]"},
+		"Accept":        {"*/*"},
+	}, payloads.Header(http.Header{
+		"Authorization": {"Bearer abc"},
+		"Accept":        {"*/*"},
+	}))
+	assert.Equal(t, url.Values{"token": {"[REDACTED]"}, "page": {"2"}},
+		payloads.Query(url.Values{"token": {"t"}, "page": {"2"}}))
+	assert.Equal(t, "abcd...(2 more bytes)", payloads.Body([]byte("abcdef")))
+}
+
+func Test_WithPayloads(t *testing.T) {
+	assert.False(t, lambdalog.PayloadsFromContext(context.Background()).Disabled)
+
+	ctx := lambdalog.WithPayloads(context.Background(), lambdalog.Payloads{SampleRate: 1})
+	assert.False(t, lambdalog.PayloadsFromContext(ctx).Disabled)
+
+	ctx = lambdalog.WithPayloads(context.Background(), lambdalog.Payloads{SampleRate: 1e-12})
+	assert.True(t, lambdalog.PayloadsFromContext(ctx).Disabled)
+}
@@ -0,0 +1,68 @@
+package lambdamiddleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var (
+	ErrPayloadTooLarge = errors.New("decompressed payload too large")
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Decompress gunzips payloads that arrive gzipped, either as raw bytes or as
+// a JSON string holding base64, before the handler sees them. Payloads that
+// would decompress to more than maxSize bytes fail with ErrPayloadTooLarge.
+func Decompress(maxSize int64) func(lambda.Handler) lambda.Handler {
+	return func(next lambda.Handler) lambda.Handler {
+		return HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			compressed, ok := gzippedPayload(payload)
+			if !ok {
+				return next.Invoke(ctx, payload)
+			}
+
+			reader, err := gzip.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read gzipped payload: %w", err)
+			}
+			defer reader.Close()
+			decompressed, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress payload: %w", err)
+			}
+			if int64(len(decompressed)) > maxSize {
+				return nil, fmt.Errorf("%w: more than %d bytes", ErrPayloadTooLarge, maxSize)
+			}
+			return next.Invoke(ctx, decompressed)
+		})
+	}
+}
+
+func gzippedPayload(payload []byte) ([]byte, bool) {
+	if bytes.HasPrefix(payload, gzipMagic) {
+		return payload, true
+	}
+
+	// "H4sI" is how base64 spells the gzip magic number
+	if !bytes.HasPrefix(payload, []byte(`"H4sI`)) {
+		return nil, false
+	}
+	var encoded string
+	if err := json.Unmarshal(payload, &encoded); err != nil {
+		return nil, false
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return compressed, true
+}
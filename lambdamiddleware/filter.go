@@ -0,0 +1,32 @@
+package lambdamiddleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var (
+	ErrEventRejected = errors.New("event rejected by filter")
+)
+
+// Filter only hands invocations whose payload accept returns true for to the
+// handler. The rest go to otherwise, or fail with ErrEventRejected when
+// otherwise is nil.
+func Filter(
+	accept func(ctx context.Context, payload []byte) bool,
+	otherwise lambda.Handler,
+) func(lambda.Handler) lambda.Handler {
+	return func(next lambda.Handler) lambda.Handler {
+		return HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			if accept(ctx, payload) {
+				return next.Invoke(ctx, payload)
+			}
+			if otherwise != nil {
+				return otherwise.Invoke(ctx, payload)
+			}
+			return nil, ErrEventRejected
+		})
+	}
+}
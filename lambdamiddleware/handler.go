@@ -0,0 +1,26 @@
+package lambdamiddleware
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// HandlerFunc adapts a function to lambda.Handler.
+type HandlerFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+var _ lambda.Handler = HandlerFunc(nil)
+
+func (f HandlerFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
+// Wrap applies middlewares to handler in order, so the last one sees each
+// invocation first. It is how the HTTP bridge applies its LambdaMiddleware
+// option, for handlers that don't go through the bridge.
+func Wrap(handler lambda.Handler, middlewares ...func(lambda.Handler) lambda.Handler) lambda.Handler {
+	for _, middleware := range middlewares {
+		handler = middleware(handler)
+	}
+	return handler
+}
@@ -0,0 +1,60 @@
+package lambdamiddleware
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Invocation describes one invocation for Metrics.
+type Invocation struct {
+	ColdStart    bool
+	Duration     time.Duration
+	PayloadSize  int
+	ResponseSize int
+	Err          error
+}
+
+// Metrics reports every invocation to record once the handler returns. Cold
+// starts are only reported when ColdStart wraps it.
+func Metrics(record func(ctx context.Context, invocation Invocation)) func(lambda.Handler) lambda.Handler {
+	return func(next lambda.Handler) lambda.Handler {
+		return HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			start := time.Now()
+			resp, err := next.Invoke(ctx, payload)
+			record(ctx, Invocation{
+				ColdStart:    IsColdStart(ctx),
+				Duration:     time.Since(start),
+				PayloadSize:  len(payload),
+				ResponseSize: len(resp),
+				Err:          err,
+			})
+			return resp, err
+		})
+	}
+}
+
+type coldStartKey struct{}
+
+// ColdStart marks the first invocation it sees as the execution
+// environment's cold start, for IsColdStart, whatever the middlewares it
+// wraps do with it. The bridge applies it outside every LambdaMiddleware;
+// handlers that don't go through the bridge should wrap it outermost.
+func ColdStart() func(lambda.Handler) lambda.Handler {
+	var invoked atomic.Bool
+	return func(next lambda.Handler) lambda.Handler {
+		return HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			ctx = context.WithValue(ctx, coldStartKey{}, !invoked.Swap(true))
+			return next.Invoke(ctx, payload)
+		})
+	}
+}
+
+// IsColdStart reports whether ctx belongs to the first invocation ColdStart
+// saw. It is false for invocations ColdStart didn't see at all.
+func IsColdStart(ctx context.Context) bool {
+	coldStart, _ := ctx.Value(coldStartKey{}).(bool)
+	return coldStart
+}
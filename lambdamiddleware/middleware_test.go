@@ -0,0 +1,172 @@
+package lambdamiddleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/lambdamiddleware"
+)
+
+var echo = lambdamiddleware.HandlerFunc(func(_ context.Context, payload []byte) ([]byte, error) {
+	return payload, nil
+})
+
+func Test_Filter(t *testing.T) {
+	onlyObjects := func(_ context.Context, payload []byte) bool {
+		return bytes.HasPrefix(payload, []byte("{"))
+	}
+	rejected := lambdamiddleware.HandlerFunc(func(context.Context, []byte) ([]byte, error) {
+		return []byte("rejected"), nil
+	})
+
+	handler := lambdamiddleware.Filter(onlyObjects, nil)(echo)
+	resp, err := handler.Invoke(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(resp))
+	_, err = handler.Invoke(context.Background(), []byte(`[]`))
+	assert.ErrorIs(t, err, lambdamiddleware.ErrEventRejected)
+
+	handler = lambdamiddleware.Filter(onlyObjects, rejected)(echo)
+	resp, err = handler.Invoke(context.Background(), []byte(`[]`))
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", string(resp))
+}
+
+func Test_WarmUp(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		wantHandled bool
+	}{
+		{name: "serverless-plugin-warmup", payload: `{"source": "serverless-plugin-warmup"}`},
+		{name: "lambda-warmer", payload: `{"warmer": true, "concurrency": 1}`},
+		{name: "http event", payload: `{"version": "2.0", "rawPath": "/"}`, wantHandled: true},
+		{name: "not json", payload: `nope`, wantHandled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			handler := lambdamiddleware.WarmUp(nil)(lambdamiddleware.HandlerFunc(
+				func(context.Context, []byte) ([]byte, error) {
+					handled = true
+					return nil, nil
+				},
+			))
+			_, err := handler.Invoke(context.Background(), []byte(tt.payload))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHandled, handled)
+		})
+	}
+}
+
+func Test_Decompress(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte(`{"hello": "world"}`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	encoded, err := json.Marshal(base64.StdEncoding.EncodeToString(compressed.Bytes()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		payload []byte
+		want    string
+		wantErr error
+	}{
+		{name: "plain", maxSize: 1024, payload: []byte(`{}`), want: `{}`},
+		{name: "raw gzip", maxSize: 1024, payload: compressed.Bytes(), want: `{"hello": "world"}`},
+		{name: "base64 gzip", maxSize: 1024, payload: encoded, want: `{"hello": "world"}`},
+		{name: "too large", maxSize: 4, payload: compressed.Bytes(), wantErr: lambdamiddleware.ErrPayloadTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := lambdamiddleware.Decompress(tt.maxSize)(echo).Invoke(context.Background(), tt.payload)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(resp))
+		})
+	}
+}
+
+func Test_Recover(t *testing.T) {
+	var gotRecovered any
+	var gotStack []byte
+	handler := lambdamiddleware.Recover(func(_ context.Context, recovered any, stack []byte) {
+		gotRecovered, gotStack = recovered, stack
+	})(lambdamiddleware.HandlerFunc(func(context.Context, []byte) ([]byte, error) {
+		panic("boom")
+	}))
+
+	_, err := handler.Invoke(context.Background(), []byte(`{}`))
+	assert.ErrorIs(t, err, lambdamiddleware.ErrPanic)
+	assert.Equal(t, "boom", gotRecovered)
+	assert.Contains(t, string(gotStack), "middleware_test.go")
+}
+
+func Test_Metrics(t *testing.T) {
+	var got []lambdamiddleware.Invocation
+	failing := errors.New("failing")
+	handler := lambdamiddleware.Wrap(
+		lambdamiddleware.HandlerFunc(func(_ context.Context, payload []byte) ([]byte, error) {
+			if string(payload) == "fail" {
+				return nil, failing
+			}
+			return []byte("ok"), nil
+		}),
+		lambdamiddleware.Metrics(func(_ context.Context, invocation lambdamiddleware.Invocation) {
+			got = append(got, invocation)
+		}),
+		lambdamiddleware.ColdStart(),
+	)
+
+	for i, payload := range []string{"first", "fail"} {
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+			AwsRequestID: []string{"request-1", "request-2"}[i],
+		})
+		_, _ = handler.Invoke(ctx, []byte(payload))
+	}
+
+	require.Len(t, got, 2)
+	assert.True(t, got[0].ColdStart)
+	assert.Equal(t, 5, got[0].PayloadSize)
+	assert.Equal(t, 2, got[0].ResponseSize)
+	assert.NoError(t, got[0].Err)
+	assert.False(t, got[1].ColdStart)
+	assert.ErrorIs(t, got[1].Err, failing)
+}
+
+func Test_ColdStart(t *testing.T) {
+	var got []bool
+	handler := lambdamiddleware.Wrap(
+		lambdamiddleware.HandlerFunc(func(ctx context.Context, _ []byte) ([]byte, error) {
+			got = append(got, lambdamiddleware.IsColdStart(ctx))
+			return []byte("ok"), nil
+		}),
+		lambdamiddleware.WarmUp(nil),
+		lambdamiddleware.ColdStart(),
+	)
+
+	// the first invocation is a ping that never reaches the handler to ask
+	for _, payload := range []string{`{"warmer":true}`, `{}`, `{}`} {
+		_, err := handler.Invoke(context.Background(), []byte(payload))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []bool{false, false}, got)
+	assert.False(t, lambdamiddleware.IsColdStart(context.Background()))
+}
@@ -0,0 +1,48 @@
+package lambdamiddleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var (
+	ErrPanic = errors.New("handler panicked")
+)
+
+// Recover turns a panic in the handler into an ordinary invocation error.
+// Left alone, the runtime reports the panic and exits, so the next invocation
+// pays for a cold start. onPanic, when not nil, is told about every panic,
+// e.g. to count it or send it to an error tracker.
+func Recover(onPanic func(ctx context.Context, recovered any, stack []byte)) func(lambda.Handler) lambda.Handler {
+	return func(next lambda.Handler) lambda.Handler {
+		return HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			var recovered any
+			var stack []byte
+			resp, err := func() ([]byte, error) {
+				defer func() {
+					if recovered = recover(); recovered != nil {
+						stack = debug.Stack()
+					}
+				}()
+				return next.Invoke(ctx, payload)
+			}()
+			if recovered == nil {
+				return resp, err
+			}
+
+			slog.ErrorContext(ctx, "recovered from panic in lambda handler",
+				"panic", fmt.Sprint(recovered),
+				"stack", string(stack),
+			)
+			if onPanic != nil {
+				onPanic(ctx, recovered, stack)
+			}
+			return nil, fmt.Errorf("%w: %v", ErrPanic, recovered)
+		})
+	}
+}
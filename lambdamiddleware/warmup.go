@@ -0,0 +1,41 @@
+package lambdamiddleware
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var warmUpResponse = []byte(`{"warm":true}`)
+
+// WarmUp answers warm-up pings without invoking the handler at all. A nil
+// isPing uses IsWarmUpPing.
+func WarmUp(isPing func(payload []byte) bool) func(lambda.Handler) lambda.Handler {
+	if isPing == nil {
+		isPing = IsWarmUpPing
+	}
+	return func(next lambda.Handler) lambda.Handler {
+		return HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			if isPing(payload) {
+				slog.DebugContext(ctx, "answered warm-up ping")
+				return warmUpResponse, nil
+			}
+			return next.Invoke(ctx, payload)
+		})
+	}
+}
+
+// IsWarmUpPing recognizes the pings sent by serverless-plugin-warmup and
+// lambda-warmer.
+func IsWarmUpPing(payload []byte) bool {
+	var ping struct {
+		Source string `json:"source"`
+		Warmer bool   `json:"warmer"`
+	}
+	if err := json.Unmarshal(payload, &ping); err != nil {
+		return false
+	}
+	return ping.Source == "serverless-plugin-warmup" || ping.Warmer
+}
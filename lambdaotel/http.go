@@ -0,0 +1,102 @@
+package lambdaotel
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/felixge/httpsnoop"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/geode-io/golambdas/lambdalog"
+)
+
+// HTTP starts a span for every request. Inside Invocation's span it is a
+// child of it, linked to whatever trace context the request headers carry;
+// outside, the headers' context is its parent.
+func HTTP(opts ...Option) func(http.Handler) http.Handler {
+	useOpts := newOptions(opts)
+	tracer := useOpts.tracer()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			remote := trace.SpanContextFromContext(
+				useOpts.propagator.Extract(ctx, propagation.HeaderCarrier(r.Header)),
+			)
+			startOpts := []trace.SpanStartOption{
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(requestAttrs(r)...),
+			}
+			switch {
+			case !remote.IsValid():
+			case trace.SpanContextFromContext(ctx).IsValid():
+				startOpts = append(startOpts, trace.WithLinks(trace.Link{SpanContext: remote}))
+			default:
+				ctx = trace.ContextWithRemoteSpanContext(ctx, remote)
+			}
+
+			ctx, span := tracer.Start(ctx, r.Method, startOpts...)
+			defer span.End()
+			// read before next can rewrite it
+			method := r.Method
+			r = r.WithContext(ctx)
+			snoop := httpsnoop.CaptureMetrics(next, w, r)
+
+			var route string
+			if useOpts.route != nil {
+				route = useOpts.route(r)
+			}
+			if route != "" {
+				span.SetName(method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			span.SetAttributes(
+				semconv.HTTPResponseStatusCode(snoop.Code),
+				semconv.HTTPResponseBodySize(int(snoop.Written)),
+			)
+			if snoop.Code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(snoop.Code))
+			}
+		})
+	}
+}
+
+func requestAttrs(r *http.Request) []attribute.KeyValue {
+	scheme := "http"
+	if r.URL.Scheme != "" {
+		scheme = r.URL.Scheme
+	} else if r.TLS != nil {
+		scheme = "https"
+	}
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(scheme),
+		semconv.ServerAddress(r.Host),
+	}
+	if r.URL.RawQuery != "" {
+		rawQuery := lambdalog.PayloadsFromContext(r.Context()).RawQuery(r.URL.RawQuery)
+		attrs = append(attrs, semconv.URLQuery(rawQuery))
+	}
+	if host, port, err := net.SplitHostPort(r.Host); err == nil {
+		attrs[len(attrs)-1] = semconv.ServerAddress(host)
+		if port, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.ServerPort(port))
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		attrs = append(attrs, semconv.ClientAddress(host))
+	}
+	if userAgent := r.UserAgent(); userAgent != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(userAgent))
+	}
+	if r.ContentLength > 0 {
+		attrs = append(attrs, semconv.HTTPRequestBodySize(int(r.ContentLength)))
+	}
+	return attrs
+}
@@ -0,0 +1,99 @@
+package lambdaotel
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/geode-io/golambdas/lambdamiddleware"
+	"github.com/geode-io/golambdas/tracing"
+)
+
+type flusher interface {
+	ForceFlush(ctx context.Context) error
+}
+
+// Invocation starts a span for every invocation, continuing the X-Ray trace
+// Lambda started for it, and flushes the tracer provider before returning,
+// even from a panic, since the execution environment may be frozen as soon as
+// it has.
+func Invocation(opts ...Option) func(lambda.Handler) lambda.Handler {
+	useOpts := newOptions(opts)
+
+	return func(next lambda.Handler) lambda.Handler {
+		return lambdamiddleware.HandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+			tracerProvider := useOpts.provider()
+			ctx = xray.Propagator{}.Extract(ctx, propagation.MapCarrier{
+				tracing.HeaderName: tracing.InvocationHeader(ctx).String(),
+			})
+			ctx, span := tracerProvider.Tracer(instrumentationName).Start(ctx, lambdacontext.FunctionName,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(invocationAttrs(ctx, useOpts.trigger)...),
+			)
+			defer func() {
+				span.End()
+				if f, ok := tracerProvider.(flusher); ok {
+					_ = f.ForceFlush(ctx)
+				}
+			}()
+
+			resp, err := next.Invoke(ctx, payload)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return resp, err
+		})
+	}
+}
+
+func invocationAttrs(ctx context.Context, trigger attribute.KeyValue) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.CloudProviderAWS,
+		semconv.CloudPlatformAWSLambda,
+		semconv.FaaSColdstart(lambdamiddleware.IsColdStart(ctx)),
+		semconv.FaaSName(lambdacontext.FunctionName),
+		semconv.FaaSVersion(lambdacontext.FunctionVersion),
+	}
+	if trigger.Valid() {
+		attrs = append(attrs, trigger)
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		attrs = append(attrs, semconv.CloudRegion(region))
+	}
+	if logStream := lambdacontext.LogStreamName; logStream != "" {
+		attrs = append(attrs, semconv.FaaSInstance(logStream))
+	}
+	if lambdacontext.MemoryLimitInMB > 0 {
+		attrs = append(attrs, semconv.FaaSMaxMemory(lambdacontext.MemoryLimitInMB<<20))
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		attrs = append(attrs, semconv.FaaSInvocationID(lc.AwsRequestID))
+		if lc.InvokedFunctionArn != "" {
+			attrs = append(attrs, semconv.CloudResourceID(lc.InvokedFunctionArn))
+			if accountID := arnAccountID(lc.InvokedFunctionArn); accountID != "" {
+				attrs = append(attrs, semconv.CloudAccountID(accountID))
+			}
+		}
+	}
+	return attrs
+}
+
+// arnAccountID returns the account of an ARN such as
+// "arn:aws:lambda:eu-west-1:123456789012:function:my-function".
+func arnAccountID(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
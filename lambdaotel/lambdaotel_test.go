@@ -0,0 +1,160 @@
+package lambdaotel_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/geode-io/golambdas/httpbridge"
+	"github.com/geode-io/golambdas/lambdalog"
+	"github.com/geode-io/golambdas/lambdamiddleware"
+	"github.com/geode-io/golambdas/lambdaotel"
+)
+
+const (
+	xrayHeader  = "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+	xrayTraceID = "5759e988bd862e3fe1be46a994272793"
+	traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+)
+
+func newTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	// batched, so only the flush at the end of the invocation exports anything
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	t.Cleanup(func() { _ = tracerProvider.Shutdown(context.Background()) })
+	return tracerProvider, exporter
+}
+
+func attrsOf(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func Test_Invocation(t *testing.T) {
+	tracerProvider, exporter := newTracerProvider(t)
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID:       "3c1d2b0e-7a6f-4e8b-9c1d-5e4f3a2b1c0d",
+		InvokedFunctionArn: "arn:aws:lambda:eu-west-1:123456789012:function:pets",
+	})
+	ctx = context.WithValue(ctx, "x-amzn-trace-id", xrayHeader) //nolint:staticcheck // the runtime's key
+
+	failing := lambdamiddleware.HandlerFunc(func(context.Context, []byte) ([]byte, error) {
+		return nil, errors.New("nope")
+	})
+	invocation := lambdaotel.Invocation(
+		lambdaotel.TracerProvider(tracerProvider),
+		lambdaotel.Trigger(semconv.FaaSTriggerPubSub),
+	)
+	_, err := invocation(failing).Invoke(ctx, []byte(`{}`))
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, xrayTraceID, span.SpanContext.TraceID().String())
+	assert.Equal(t, "53995c3f42cd8ad8", span.Parent.SpanID().String())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, codes.Error, span.Status.Code)
+
+	attrs := attrsOf(span)
+	assert.Equal(t, "aws_lambda", attrs["cloud.platform"].AsString())
+	assert.Equal(t, "pubsub", attrs["faas.trigger"].AsString())
+	assert.Equal(t, "3c1d2b0e-7a6f-4e8b-9c1d-5e4f3a2b1c0d", attrs["faas.invocation_id"].AsString())
+	assert.Equal(t, "123456789012", attrs["cloud.account.id"].AsString())
+	assert.Contains(t, attrs, attribute.Key("faas.coldstart"))
+}
+
+func Test_Invocation_Panics(t *testing.T) {
+	tracerProvider, exporter := newTracerProvider(t)
+	panicking := lambdamiddleware.HandlerFunc(func(context.Context, []byte) ([]byte, error) {
+		panic("boom")
+	})
+
+	assert.Panics(t, func() {
+		_, _ = lambdaotel.Invocation(lambdaotel.TracerProvider(tracerProvider))(panicking).
+			Invoke(context.Background(), []byte(`{}`))
+	})
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.NotContains(t, attrsOf(spans[0]), attribute.Key("faas.trigger"))
+}
+
+func Test_Invocation_GlobalTracerProvider(t *testing.T) {
+	// built before the global tracer provider is set, as package level
+	// handlers are
+	invocation := lambdaotel.Invocation()(lambdamiddleware.HandlerFunc(func(context.Context, []byte) ([]byte, error) {
+		return []byte("ok"), nil
+	}))
+	tracerProvider, exporter := newTracerProvider(t)
+	otel.SetTracerProvider(tracerProvider)
+
+	_, err := invocation.Invoke(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.Len(t, exporter.GetSpans(), 1)
+}
+
+func Test_HTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pets/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	t.Run("continues the caller's trace", func(t *testing.T) {
+		tracerProvider, exporter := newTracerProvider(t)
+		ctx := lambdalog.WithPayloads(context.Background(), lambdalog.Payloads{RedactQueryParams: []string{"token"}})
+		r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/pets/7?token=secret&page=2", nil)
+		r.Header.Set("traceparent", traceparent)
+		lambdaotel.HTTP(lambdaotel.TracerProvider(tracerProvider), lambdaotel.Route(httpbridge.Route))(mux).
+			ServeHTTP(httptest.NewRecorder(), r)
+		require.NoError(t, tracerProvider.ForceFlush(context.Background()))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		assert.Equal(t, "GET /pets/{id}", span.Name)
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext.TraceID().String())
+		attrs := attrsOf(span)
+		assert.Equal(t, "/pets/{id}", attrs["http.route"].AsString())
+		assert.Equal(t, int64(http.StatusTeapot), attrs["http.response.status_code"].AsInt64())
+		assert.Equal(t, "/pets/7", attrs["url.path"].AsString())
+		assert.Equal(t, "token=%5BREDACTED%5D&page=2", attrs["url.query"].AsString())
+	})
+
+	t.Run("nests inside the invocation", func(t *testing.T) {
+		tracerProvider, exporter := newTracerProvider(t)
+		invocation := lambdaotel.Invocation(lambdaotel.TracerProvider(tracerProvider))(
+			lambdamiddleware.HandlerFunc(func(ctx context.Context, _ []byte) ([]byte, error) {
+				r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/pets/7", nil)
+				r.Header.Set("traceparent", traceparent)
+				lambdaotel.HTTP(lambdaotel.TracerProvider(tracerProvider))(mux).ServeHTTP(httptest.NewRecorder(), r)
+				return nil, nil
+			}),
+		)
+		_, err := invocation.Invoke(context.Background(), nil)
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		httpSpan, invocationSpan := spans[0], spans[1]
+		assert.Equal(t, invocationSpan.SpanContext.SpanID(), httpSpan.Parent.SpanID())
+		require.Len(t, httpSpan.Links, 1)
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", httpSpan.Links[0].SpanContext.TraceID().String())
+	})
+}
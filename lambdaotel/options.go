@@ -0,0 +1,80 @@
+package lambdaotel
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/geode-io/golambdas/lambdaotel"
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	route          func(r *http.Request) string
+	trigger        attribute.KeyValue
+}
+
+type Option func(*options)
+
+func newOptions(opts []Option) options {
+	useOpts := options{
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, xray.Propagator{}),
+	}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+	return useOpts
+}
+
+// provider is the TracerProvider option, or else the global tracer provider as
+// it is now, which may have been set since the options were.
+func (o options) provider() trace.TracerProvider {
+	if o.tracerProvider != nil {
+		return o.tracerProvider
+	}
+	return otel.GetTracerProvider()
+}
+
+func (o options) tracer() trace.Tracer {
+	return o.provider().Tracer(instrumentationName)
+}
+
+// TracerProvider replaces the global tracer provider, as set when each
+// invocation starts. Whatever exporter it has, it is flushed at the end of
+// every invocation if it can be.
+func TracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tracerProvider
+	}
+}
+
+// Propagator replaces the W3C trace context and X-Ray propagators that
+// request headers are extracted with.
+func Propagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = propagator
+	}
+}
+
+// Route names the route template a request matched, once served, for the
+// http.route attribute and the span name, such as httpbridge.Route, which the
+// bridge's Tracing option uses.
+func Route(route func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.route = route
+	}
+}
+
+// Trigger sets the faas.trigger attribute of invocation spans, such as
+// semconv.FaaSTriggerPubSub for SQS events. Spans have none without it; the
+// bridge's Tracing option sets semconv.FaaSTriggerHTTP.
+func Trigger(trigger attribute.KeyValue) Option {
+	return func(o *options) {
+		o.trigger = trigger
+	}
+}
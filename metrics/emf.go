@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CloudWatch won't extract more than this many metrics from one record, nor
+// more than this many values for one metric.
+const maxMetricsPerRecord = 100
+
+const maxDimensionsPerSet = 30
+
+var (
+	ErrTooManyDimensions = errors.New("dimension sets hold at most 30 dimensions")
+)
+
+type Unit string
+
+const (
+	UnitNone           Unit = "None"
+	UnitCount          Unit = "Count"
+	UnitPercent        Unit = "Percent"
+	UnitSeconds        Unit = "Seconds"
+	UnitMilliseconds   Unit = "Milliseconds"
+	UnitMicroseconds   Unit = "Microseconds"
+	UnitBytes          Unit = "Bytes"
+	UnitKilobytes      Unit = "Kilobytes"
+	UnitMegabytes      Unit = "Megabytes"
+	UnitBytesPerSecond Unit = "Bytes/Second"
+	UnitCountPerSecond Unit = "Count/Second"
+)
+
+// Resolution is how finely CloudWatch stores a metric, in seconds.
+type Resolution int
+
+const (
+	ResolutionStandard Resolution = 60
+	ResolutionHigh     Resolution = 1
+)
+
+type Dimension struct {
+	Name  string
+	Value string
+}
+
+type metric struct {
+	name       string
+	unit       Unit
+	resolution Resolution
+	values     []float64
+}
+
+// Logger collects metrics and writes them as CloudWatch Embedded Metric
+// Format records on Flush, for CloudWatch Logs to extract without an agent or
+// API calls. It is safe for concurrent use.
+type Logger struct {
+	mu            sync.Mutex
+	w             io.Writer
+	namespace     string
+	dimensionSets [][]Dimension
+	properties    map[string]any
+	metrics       []*metric
+	now           func() time.Time
+}
+
+// NewLogger returns a Logger writing to w, os.Stdout when nil.
+func NewLogger(w io.Writer, namespace string) *Logger {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &Logger{
+		w:          w,
+		namespace:  namespace,
+		properties: make(map[string]any),
+		now:        time.Now,
+	}
+}
+
+// AddDimensions adds a set of dimensions every metric is published under.
+func (l *Logger) AddDimensions(dimensions ...Dimension) error {
+	if len(dimensions) > maxDimensionsPerSet {
+		return ErrTooManyDimensions
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dimensionSets = append(l.dimensionSets, slices.Clone(dimensions))
+	return nil
+}
+
+// SetProperty adds a field to every record that isn't a metric or dimension,
+// such as a request ID to find the record by.
+func (l *Logger) SetProperty(key string, value any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.properties[key] = value
+}
+
+// Put records a value of the metric name at standard resolution. Values put
+// for the same name before a Flush are published together, in the unit the
+// first was put in.
+func (l *Logger) Put(name string, value float64, unit Unit) {
+	l.PutWithResolution(name, value, unit, ResolutionStandard)
+}
+
+// PutWithResolution is Put for high-resolution metrics.
+func (l *Logger) PutWithResolution(name string, value float64, unit Unit, resolution Resolution) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range l.metrics {
+		if m.name == name {
+			m.values = append(m.values, value)
+			return
+		}
+	}
+	l.metrics = append(l.metrics, &metric{name: name, unit: unit, resolution: resolution, values: []float64{value}})
+}
+
+// Flush writes what was put since the last Flush, in as many records as it
+// takes to stay within 100 metrics and 100 values per metric each.
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.metrics) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for batch := 0; ; batch++ {
+		var pending []*metric
+		for _, m := range l.metrics {
+			if len(m.values) > batch*maxMetricsPerRecord {
+				pending = append(pending, m)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		for chunk := range slices.Chunk(pending, maxMetricsPerRecord) {
+			record, err := json.Marshal(l.record(chunk, batch))
+			if err != nil {
+				return fmt.Errorf("failed to marshal metrics: %w", err)
+			}
+			buf.Write(record)
+			buf.WriteByte('\n')
+		}
+	}
+	l.metrics = nil
+
+	if _, err := l.w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+type metricDefinition struct {
+	Name              string `json:"Name"`
+	Unit              Unit   `json:"Unit,omitempty"`
+	StorageResolution int    `json:"StorageResolution,omitempty"`
+}
+
+func (l *Logger) record(metrics []*metric, batch int) map[string]any {
+	record := make(map[string]any, len(l.properties)+len(metrics)+1)
+	for key, value := range l.properties {
+		record[key] = value
+	}
+
+	directive := metricDirective{Namespace: l.namespace, Dimensions: [][]string{}}
+	for _, set := range l.dimensionSets {
+		names := make([]string, 0, len(set))
+		for _, dimension := range set {
+			names = append(names, dimension.Name)
+			record[dimension.Name] = dimension.Value
+		}
+		directive.Dimensions = append(directive.Dimensions, names)
+	}
+
+	for _, m := range metrics {
+		definition := metricDefinition{Name: m.name, Unit: m.unit}
+		if m.resolution == ResolutionHigh {
+			definition.StorageResolution = int(ResolutionHigh)
+		}
+		directive.Metrics = append(directive.Metrics, definition)
+
+		values := m.values[batch*maxMetricsPerRecord:]
+		values = values[:min(len(values), maxMetricsPerRecord)]
+		if len(values) == 1 {
+			record[m.name] = values[0]
+		} else {
+			record[m.name] = values
+		}
+	}
+
+	record["_aws"] = map[string]any{
+		"Timestamp":         l.now().UnixMilli(),
+		"CloudWatchMetrics": []metricDirective{directive},
+	}
+	return record
+}
@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/metrics"
+)
+
+type emfRecord struct {
+	AWS struct {
+		Timestamp         int64 `json:"Timestamp"`
+		CloudWatchMetrics []struct {
+			Namespace  string     `json:"Namespace"`
+			Dimensions [][]string `json:"Dimensions"`
+			Metrics    []struct {
+				Name              string `json:"Name"`
+				Unit              string `json:"Unit"`
+				StorageResolution int    `json:"StorageResolution"`
+			} `json:"Metrics"`
+		} `json:"CloudWatchMetrics"`
+	} `json:"_aws"`
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]json.RawMessage {
+	t.Helper()
+	var out []map[string]json.RawMessage
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		out = append(out, record)
+	}
+	return out
+}
+
+func Test_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := metrics.NewLogger(&buf, "Pets")
+	require.NoError(t, logger.AddDimensions(metrics.Dimension{Name: "Service", Value: "pets"}))
+	logger.SetProperty("requestId", "8e1c5a2f")
+	logger.Put("Orders", 1, metrics.UnitCount)
+	logger.Put("Orders", 2, metrics.UnitCount)
+	logger.PutWithResolution("Latency", 12.5, metrics.UnitMilliseconds, metrics.ResolutionHigh)
+	require.NoError(t, logger.Flush())
+
+	lines := records(t, &buf)
+	require.Len(t, lines, 1)
+	line := lines[0]
+	assert.JSONEq(t, `"pets"`, string(line["Service"]))
+	assert.JSONEq(t, `"8e1c5a2f"`, string(line["requestId"]))
+	assert.JSONEq(t, `[1, 2]`, string(line["Orders"]))
+	assert.JSONEq(t, `12.5`, string(line["Latency"]))
+
+	var record emfRecord
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.NotZero(t, record.AWS.Timestamp)
+	require.Len(t, record.AWS.CloudWatchMetrics, 1)
+	directive := record.AWS.CloudWatchMetrics[0]
+	assert.Equal(t, "Pets", directive.Namespace)
+	assert.Equal(t, [][]string{{"Service"}}, directive.Dimensions)
+	require.Len(t, directive.Metrics, 2)
+	assert.Equal(t, "Count", directive.Metrics[0].Unit)
+	assert.Zero(t, directive.Metrics[0].StorageResolution)
+	assert.Equal(t, 1, directive.Metrics[1].StorageResolution)
+
+	buf.Reset()
+	require.NoError(t, logger.Flush())
+	assert.Empty(t, buf.String(), "flushed metrics are gone")
+}
+
+func Test_Logger_Batching(t *testing.T) {
+	tests := []struct {
+		name      string
+		put       func(logger *metrics.Logger)
+		wantLines int
+	}{
+		{
+			name: "150 metrics",
+			put: func(logger *metrics.Logger) {
+				for i := range 150 {
+					logger.Put(fmt.Sprintf("M%d", i), 1, metrics.UnitCount)
+				}
+			},
+			wantLines: 2,
+		},
+		{
+			name: "250 values of one metric",
+			put: func(logger *metrics.Logger) {
+				for i := range 250 {
+					logger.Put("M", float64(i), metrics.UnitCount)
+				}
+			},
+			wantLines: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := metrics.NewLogger(&buf, "Pets")
+			tt.put(logger)
+			require.NoError(t, logger.Flush())
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, tt.wantLines)
+			for _, line := range lines {
+				var record emfRecord
+				require.NoError(t, json.Unmarshal([]byte(line), &record))
+				assert.LessOrEqual(t, len(record.AWS.CloudWatchMetrics[0].Metrics), 100)
+
+				var values map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &values))
+				if v, ok := values["M"].([]any); ok {
+					assert.LessOrEqual(t, len(v), 100)
+				}
+			}
+		})
+	}
+}
+
+func Test_Logger_TooManyDimensions(t *testing.T) {
+	dimensions := make([]metrics.Dimension, 31)
+	assert.ErrorIs(t, metrics.NewLogger(nil, "Pets").AddDimensions(dimensions...), metrics.ErrTooManyDimensions)
+}
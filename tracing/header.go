@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// HeaderName is the header X-Ray trace context travels in.
+const HeaderName = "X-Amzn-Trace-Id"
+
+// the runtime stores the invocation's X-Ray header under a plain string key
+const invocationHeaderContextKey = "x-amzn-trace-id"
+
+const invocationHeaderEnv = "_X_AMZN_TRACE_ID"
+
+// TraceHeader is a parsed X-Amzn-Trace-Id header such as
+// "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1".
+type TraceHeader struct {
+	Root   string
+	Parent string
+	// Sampled is "1", "0", "?" when the caller left the decision to us, or
+	// empty.
+	Sampled string
+	// fields we don't know, such as Lineage, passed on as they were
+	extra []string
+}
+
+// ParseTraceHeader parses header, ignoring fields it can't make sense of.
+func ParseTraceHeader(header string) TraceHeader {
+	var h TraceHeader
+	for _, field := range strings.Split(header, ";") {
+		field = strings.TrimSpace(field)
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Root":
+			h.Root = value
+		case "Parent":
+			h.Parent = value
+		case "Sampled":
+			h.Sampled = value
+		default:
+			h.extra = append(h.extra, field)
+		}
+	}
+	return h
+}
+
+func (h TraceHeader) String() string {
+	fields := make([]string, 0, 3+len(h.extra))
+	if h.Root != "" {
+		fields = append(fields, "Root="+h.Root)
+	}
+	if h.Parent != "" {
+		fields = append(fields, "Parent="+h.Parent)
+	}
+	if h.Sampled != "" {
+		fields = append(fields, "Sampled="+h.Sampled)
+	}
+	return strings.Join(append(fields, h.extra...), ";")
+}
+
+// InvocationHeader returns the header Lambda traces the invocation in ctx
+// with, which is in the environment rather than ctx outside the Go runtime.
+func InvocationHeader(ctx context.Context) TraceHeader {
+	header, _ := ctx.Value(invocationHeaderContextKey).(string)
+	if header == "" {
+		header = os.Getenv(invocationHeaderEnv)
+	}
+	return ParseTraceHeader(header)
+}
+
+// IsSampled reports whether segments of the trace should be sent. Only an
+// explicit "Sampled=0" says not.
+func (h TraceHeader) IsSampled() bool {
+	return h.Sampled != "0"
+}
+
+// newTraceID returns an X-Ray trace ID, which starts with the epoch time it
+// was made at.
+func newTraceID() string {
+	return fmt.Sprintf("1-%08x-%s", time.Now().Unix(), randomHex(12))
+}
+
+func newSegmentID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/felixge/httpsnoop"
+)
+
+// HTTP traces every request in a segment of its own, a subsegment of the
+// invocation's when Lambda is tracing it and of the request's X-Amzn-Trace-Id
+// otherwise. The segment is in the request context for BeginSubsegment,
+// Transport and TraceHeaderFromContext. Like Transport's, it records the URL
+// without its query, where tokens and signatures travel.
+func HTTP(tracer *Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := InvocationHeader(r.Context())
+			if header.Root == "" {
+				header = ParseTraceHeader(r.Header.Get(HeaderName))
+			}
+			name := lambdacontext.FunctionName
+			if name == "" {
+				name = r.Host
+			}
+			ctx, segment := tracer.Begin(r.Context(), name, header)
+			clientIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+			segment.recordHTTP(func(doc *segmentDocument) {
+				doc.HTTP.Request = &httpRequestDocument{
+					Method:    r.Method,
+					URL:       withoutQuery(r.URL),
+					UserAgent: r.UserAgent(),
+					ClientIP:  clientIP,
+				}
+			})
+
+			snoop := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+			recordResponse(segment, snoop.Code, snoop.Written)
+			_ = segment.Close(nil)
+		})
+	}
+}
+
+// Transport traces every request sent through base, or
+// http.DefaultTransport when nil, in a remote subsegment of the segment in
+// its context, and tells the server about it in X-Amzn-Trace-Id.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+// Client returns a copy of client, or http.DefaultClient when nil, whose
+// requests are traced by Transport.
+func Client(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	traced := *client
+	traced.Transport = Transport(client.Transport)
+	return &traced
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, segment := BeginSubsegment(req.Context(), req.URL.Host)
+	if segment == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(ctx)
+	req.Header.Set(HeaderName, segment.TraceHeader().String())
+	segment.recordHTTP(func(doc *segmentDocument) {
+		doc.Namespace = "remote"
+		doc.HTTP.Request = &httpRequestDocument{
+			Method: req.Method,
+			URL:    withoutQuery(req.URL),
+			Traced: true,
+		}
+	})
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		_ = segment.Close(err)
+		return nil, err
+	}
+	recordResponse(segment, resp.StatusCode, resp.ContentLength)
+	_ = segment.Close(nil)
+	return resp, nil
+}
+
+func withoutQuery(u *url.URL) string {
+	stripped := *u
+	stripped.RawQuery = ""
+	stripped.ForceQuery = false
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	return stripped.String()
+}
+
+func recordResponse(segment *Segment, status int, contentLength int64) {
+	segment.recordHTTP(func(doc *segmentDocument) {
+		doc.HTTP.Response = &httpResponseDocument{Status: status, ContentLength: max(contentLength, 0)}
+		switch {
+		case status == http.StatusTooManyRequests:
+			doc.Error, doc.Throttle = true, true
+		case status >= http.StatusInternalServerError:
+			doc.Fault = true
+		case status >= http.StatusBadRequest:
+			doc.Error = true
+		}
+	})
+}
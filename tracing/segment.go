@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type segmentContextKey struct{}
+
+// Segment is a unit of traced work. Segments whose trace already has a parent,
+// like the one Lambda starts for every invocation, are sent as subsegments of
+// it. A nil *Segment, as Begin returns outside a trace, does nothing.
+type Segment struct {
+	tracer  *Tracer
+	sampled bool
+	extra   []string
+
+	mu  sync.Mutex
+	doc segmentDocument
+}
+
+type segmentDocument struct {
+	Name        string                    `json:"name"`
+	ID          string                    `json:"id"`
+	TraceID     string                    `json:"trace_id"`
+	ParentID    string                    `json:"parent_id,omitempty"`
+	Type        string                    `json:"type,omitempty"`
+	StartTime   float64                   `json:"start_time"`
+	EndTime     float64                   `json:"end_time"`
+	Namespace   string                    `json:"namespace,omitempty"`
+	Error       bool                      `json:"error,omitempty"`
+	Fault       bool                      `json:"fault,omitempty"`
+	Throttle    bool                      `json:"throttle,omitempty"`
+	Cause       *cause                    `json:"cause,omitempty"`
+	HTTP        *httpDocument             `json:"http,omitempty"`
+	Annotations map[string]any            `json:"annotations,omitempty"`
+	Metadata    map[string]map[string]any `json:"metadata,omitempty"`
+}
+
+type cause struct {
+	Exceptions []exception `json:"exceptions"`
+}
+
+type exception struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"`
+}
+
+type httpDocument struct {
+	Request  *httpRequestDocument  `json:"request,omitempty"`
+	Response *httpResponseDocument `json:"response,omitempty"`
+}
+
+type httpRequestDocument struct {
+	Method    string `json:"method,omitempty"`
+	URL       string `json:"url,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	Traced    bool   `json:"traced,omitempty"`
+}
+
+type httpResponseDocument struct {
+	Status        int   `json:"status,omitempty"`
+	ContentLength int64 `json:"content_length,omitempty"`
+}
+
+// Begin starts a segment named name continuing the trace in header: as a
+// subsegment of header's Parent if it has one, or else as a segment of its
+// own, in a new trace if header has no Root either.
+func (t *Tracer) Begin(ctx context.Context, name string, header TraceHeader) (context.Context, *Segment) {
+	s := &Segment{
+		tracer:  t,
+		sampled: header.IsSampled(),
+		extra:   header.extra,
+		doc: segmentDocument{
+			Name:      name,
+			ID:        newSegmentID(),
+			TraceID:   header.Root,
+			ParentID:  header.Parent,
+			StartTime: epochSeconds(time.Now()),
+		},
+	}
+	if s.doc.TraceID == "" {
+		s.doc.TraceID = newTraceID()
+	}
+	if s.doc.ParentID != "" {
+		s.doc.Type = "subsegment"
+	}
+	return context.WithValue(ctx, segmentContextKey{}, s), s
+}
+
+// BeginSubsegment starts a subsegment of the segment in ctx, for handler work
+// worth seeing on its own in the trace. Without one it returns a nil Segment.
+func BeginSubsegment(ctx context.Context, name string) (context.Context, *Segment) {
+	parent := SegmentFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Begin(ctx, name, parent.TraceHeader())
+}
+
+// SegmentFromContext returns the segment innermost in ctx, if any.
+func SegmentFromContext(ctx context.Context) *Segment {
+	s, _ := ctx.Value(segmentContextKey{}).(*Segment)
+	return s
+}
+
+// TraceHeaderFromContext returns the header to send downstream so their
+// segments nest under the one in ctx.
+func TraceHeaderFromContext(ctx context.Context) (TraceHeader, bool) {
+	s := SegmentFromContext(ctx)
+	if s == nil {
+		return TraceHeader{}, false
+	}
+	return s.TraceHeader(), true
+}
+
+// TraceHeader returns the header that makes downstream segments children of s.
+func (s *Segment) TraceHeader() TraceHeader {
+	if s == nil {
+		return TraceHeader{}
+	}
+	sampled := "0"
+	if s.sampled {
+		sampled = "1"
+	}
+	return TraceHeader{Root: s.doc.TraceID, Parent: s.doc.ID, Sampled: sampled, extra: s.extra}
+}
+
+// AddAnnotation indexes the segment by key, for filter expressions.
+// Annotations are strings, numbers or booleans.
+func (s *Segment) AddAnnotation(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doc.Annotations == nil {
+		s.doc.Annotations = make(map[string]any)
+	}
+	s.doc.Annotations[key] = value
+}
+
+// AddMetadata attaches value to the segment without indexing it.
+func (s *Segment) AddMetadata(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doc.Metadata == nil {
+		s.doc.Metadata = map[string]map[string]any{"default": {}}
+	}
+	s.doc.Metadata["default"][key] = value
+}
+
+// Close ends the segment, as a fault if err isn't nil, and sends it unless
+// the trace isn't sampled.
+func (s *Segment) Close(err error) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc.EndTime = epochSeconds(time.Now())
+	if err != nil {
+		s.doc.Fault = true
+		s.doc.Cause = &cause{Exceptions: []exception{{
+			ID:      newSegmentID(),
+			Message: err.Error(),
+			Type:    fmt.Sprintf("%T", err),
+		}}}
+	}
+	if !s.sampled {
+		return nil
+	}
+	return s.tracer.emit(&s.doc)
+}
+
+func (s *Segment) recordHTTP(update func(doc *segmentDocument)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doc.HTTP == nil {
+		s.doc.HTTP = &httpDocument{}
+	}
+	update(&s.doc)
+}
+
+func epochSeconds(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
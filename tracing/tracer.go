@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	daemonAddressEnv     = "AWS_XRAY_DAEMON_ADDRESS"
+	defaultDaemonAddress = "127.0.0.1:2000"
+)
+
+// every UDP datagram the daemon accepts starts with this
+var daemonHeader = []byte(`{"format":"json","version":1}` + "\n")
+
+type options struct {
+	daemonAddress string
+}
+
+type Option func(*options)
+
+// DaemonAddress sends segments to addr instead of AWS_XRAY_DAEMON_ADDRESS,
+// or 127.0.0.1:2000 when that isn't set.
+func DaemonAddress(addr string) Option {
+	return func(o *options) {
+		o.daemonAddress = addr
+	}
+}
+
+// Tracer sends segments to the X-Ray daemon over UDP, which in Lambda is
+// always running next to the function when active tracing is on.
+type Tracer struct {
+	daemonAddress string
+
+	dialOnce sync.Once
+	conn     net.Conn
+	dialErr  error
+}
+
+func NewTracer(opts ...Option) *Tracer {
+	useOpts := options{daemonAddress: daemonAddressFromEnv()}
+	for _, opt := range opts {
+		opt(&useOpts)
+	}
+	return &Tracer{daemonAddress: useOpts.daemonAddress}
+}
+
+// AWS_XRAY_DAEMON_ADDRESS is either an address, or "tcp:host:port
+// udp:host:port" when the daemon's TCP and UDP addresses differ.
+func daemonAddressFromEnv() string {
+	addr := strings.TrimSpace(os.Getenv(daemonAddressEnv))
+	for _, field := range strings.Fields(addr) {
+		if udpAddr, ok := strings.CutPrefix(field, "udp:"); ok {
+			return udpAddr
+		}
+	}
+	if addr == "" || strings.Contains(addr, " ") {
+		return defaultDaemonAddress
+	}
+	return addr
+}
+
+func (t *Tracer) emit(doc *segmentDocument) error {
+	t.dialOnce.Do(func() {
+		t.conn, t.dialErr = net.Dial("udp", t.daemonAddress)
+	})
+	if t.dialErr != nil {
+		return fmt.Errorf("failed to reach the X-Ray daemon at %s: %w", t.daemonAddress, t.dialErr)
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal segment: %w", err)
+	}
+	datagram := append(append(make([]byte, 0, len(daemonHeader)+len(body)), daemonHeader...), body...)
+	if _, err := t.conn.Write(datagram); err != nil {
+		return fmt.Errorf("failed to send segment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/geode-io/golambdas/tracing"
+	"github.com/geode-io/golambdas/tracing/tracingtest"
+)
+
+func Test_ParseTraceHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		want        tracing.TraceHeader
+		wantSampled bool
+	}{
+		{
+			name:   "lambda",
+			header: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			want: tracing.TraceHeader{
+				Root:    "1-5759e988-bd862e3fe1be46a994272793",
+				Parent:  "53995c3f42cd8ad8",
+				Sampled: "1",
+			},
+			wantSampled: true,
+		},
+		{
+			name:        "alb",
+			header:      "Root=1-58337262-36d228ad5d99923122bbe354",
+			want:        tracing.TraceHeader{Root: "1-58337262-36d228ad5d99923122bbe354"},
+			wantSampled: true,
+		},
+		{
+			name:   "not sampled",
+			header: "Root=1-58337262-36d228ad5d99923122bbe354; Sampled=0",
+			want:   tracing.TraceHeader{Root: "1-58337262-36d228ad5d99923122bbe354", Sampled: "0"},
+		},
+		{
+			name:        "garbage",
+			header:      "nonsense",
+			wantSampled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tracing.ParseTraceHeader(tt.header)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSampled, got.IsSampled())
+		})
+	}
+
+	lineage := "Root=1-58337262-36d228ad5d99923122bbe354;Parent=53995c3f42cd8ad8;Sampled=1;Lineage=a87bd80c:1"
+	assert.Equal(t, lineage, tracing.ParseTraceHeader(lineage).String())
+}
+
+func Test_HTTP(t *testing.T) {
+	daemon := tracingtest.NewDaemon(t)
+	tracer := tracing.NewTracer(tracing.DaemonAddress(daemon.Addr()))
+
+	var downstreamHeader string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamHeader = r.Header.Get(tracing.HeaderName)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer downstream.Close()
+
+	handler := tracing.HTTP(tracer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, work := tracing.BeginSubsegment(r.Context(), "load pet")
+		work.AddAnnotation("pet", "7")
+		require.NoError(t, work.Close(errors.New("cache miss")))
+
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, downstream.URL+"?X-Amz-Signature=abc", nil)
+		require.NoError(t, err)
+		resp, err := tracing.Client(nil).Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/pets/7?token=secret", nil)
+	r.Header.Set(tracing.HeaderName, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	work, remote, request := daemon.Receive(t), daemon.Receive(t), daemon.Receive(t)
+
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", request["trace_id"])
+	assert.Equal(t, "53995c3f42cd8ad8", request["parent_id"])
+	assert.Equal(t, "subsegment", request["type"])
+	assert.Equal(t, true, request["error"])
+	httpDoc, ok := request["http"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"status": float64(404)}, httpDoc["response"])
+	requestDoc, ok := httpDoc["request"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "/pets/7", requestDoc["url"])
+
+	assert.Equal(t, "load pet", work["name"])
+	assert.Equal(t, request["id"], work["parent_id"])
+	assert.Equal(t, true, work["fault"])
+	assert.Equal(t, map[string]any{"pet": "7"}, work["annotations"])
+
+	assert.Equal(t, "remote", remote["namespace"])
+	assert.Equal(t, request["id"], remote["parent_id"])
+	assert.Equal(t, true, remote["fault"])
+	remoteHTTP, ok := remote["http"].(map[string]any)
+	require.True(t, ok)
+	remoteRequest, ok := remoteHTTP["request"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, downstream.URL, remoteRequest["url"])
+	assert.Equal(t,
+		fmt.Sprintf("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=%s;Sampled=1", remote["id"]),
+		downstreamHeader)
+}
+
+func Test_HTTP_NotSampled(t *testing.T) {
+	daemon := tracingtest.NewDaemon(t)
+	tracer := tracing.NewTracer(tracing.DaemonAddress(daemon.Addr()))
+
+	var header tracing.TraceHeader
+	handler := tracing.HTTP(tracer)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		header, _ = tracing.TraceHeaderFromContext(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(tracing.HeaderName, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "0", header.Sampled)
+
+	// a sampled trace after it proves the unsampled one was never sent
+	_, segment := tracer.Begin(context.Background(), "sampled", tracing.TraceHeader{})
+	require.NoError(t, segment.Close(nil))
+	assert.Equal(t, "sampled", daemon.Receive(t)["name"])
+}
+
+func Test_BeginSubsegment_OutsideATrace(t *testing.T) {
+	ctx, segment := tracing.BeginSubsegment(context.Background(), "work")
+	assert.Nil(t, segment)
+	segment.AddAnnotation("ignored", true)
+	require.NoError(t, segment.Close(nil))
+	_, ok := tracing.TraceHeaderFromContext(ctx)
+	assert.False(t, ok)
+}
@@ -0,0 +1,67 @@
+// Package tracingtest stands in for the X-Ray daemon in tests.
+package tracingtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+const receiveTimeout = 5 * time.Second
+
+// Daemon receives segments over UDP like the X-Ray daemon does.
+type Daemon struct {
+	conn     *net.UDPConn
+	segments chan map[string]any
+}
+
+// NewDaemon listens on a free local port until the test ends.
+func NewDaemon(tb testing.TB) *Daemon {
+	tb.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		tb.Fatalf("failed to listen for segments: %v", err)
+	}
+	d := &Daemon{conn: conn, segments: make(chan map[string]any, 64)}
+	tb.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 64<<10)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				close(d.segments)
+				return
+			}
+			// a JSON header line, then the segment
+			_, body, _ := bytes.Cut(buf[:n], []byte("\n"))
+			var segment map[string]any
+			if err := json.Unmarshal(body, &segment); err == nil {
+				d.segments <- segment
+			}
+		}
+	}()
+	return d
+}
+
+// Addr is the address to hand to tracing.DaemonAddress.
+func (d *Daemon) Addr() string {
+	return d.conn.LocalAddr().String()
+}
+
+// Receive returns the next segment sent, failing the test if none arrives.
+func (d *Daemon) Receive(tb testing.TB) map[string]any {
+	tb.Helper()
+	select {
+	case segment, ok := <-d.segments:
+		if !ok {
+			tb.Fatal("daemon closed")
+		}
+		return segment
+	case <-time.After(receiveTimeout):
+		tb.Fatal("no segment received")
+	}
+	return nil
+}